@@ -3,16 +3,58 @@ package grpcpool
 import (
 	"context"
 	"sync"
+	"sync/atomic"
 	"time"
+
+	"google.golang.org/grpc"
 )
 
 type (
 	chanPool struct {
 		addr  string
 		conns chan *Conn
-		mu    sync.RWMutex
+		// mu 保护 closed, 持有读锁时才能向 conns 中放回连接, 持有写锁时关闭 conns
+		mu     sync.RWMutex
+		closed bool
+		// active 当前存活的连接数量, 包括空闲的和已被取出的
+		active int64
+		// inUse 已被取出尚未归还的连接数量
+		inUse int64
+		stats counters
+		// onReturn 连接归还时回调, useless 表示连接已被标记为不可用
+		onReturn func(useless bool)
+		// owners *grpc.ClientConn 到所属 *Conn 的映射, 供拦截器标记不可用的连接
+		owners sync.Map
+
+		factory    Factory
+		initCount  int64
+		maxCount   int64
+		getTimeout time.Duration
+
+		idleTimeout time.Duration
+		maxLifetime time.Duration
+
+		healthInterval time.Duration
+		healthService  string
+
+		// maxInFlight > 0 时为共享模式, 连接不再被独占, 而是同时分配给最多 maxInFlight 个调用方
+		maxInFlight int64
+		// smu 保护 shared 和其中连接的 inFlight
+		smu    sync.Mutex
+		shared []*Conn
+		// notify 共享模式下有连接被归还时通知等待者
+		notify chan struct{}
+
+		// emptied 存活连接数降为 0 时广播
+		emptied *signal
+		// created 新建连接时广播, lastDialErr 最近一次建立连接失败的错误
+		created     *signal
+		dialErrMu   sync.Mutex
+		lastDialErr error
 
-		factory Factory
+		// done 关闭时通知后台任务退出
+		done chan struct{}
+		wg   sync.WaitGroup
 	}
 )
 
@@ -21,94 +63,304 @@ func NewPool(addr string, opts ...OptFn) (Pool, error) {
 	for _, fn := range opts {
 		fn(&op)
 	}
+	c, err := newChanPool(addr, op, nil)
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// newChanPool 创建连接池, created 用于通知新建了连接, 为 nil 时单独创建
+func newChanPool(addr string, op Options, created *signal) (*chanPool, error) {
 	if op.initCount < 0 || op.maxCount <= 0 || op.initCount > op.maxCount {
 		return nil, ErrInvalidPoolSetting
 	}
 	c := &chanPool{
-		addr:    addr,
-		conns:   make(chan *Conn, op.maxCount),
-		factory: op.dial,
+		addr:       addr,
+		conns:      make(chan *Conn, op.maxCount),
+		initCount:  int64(op.initCount),
+		maxCount:   int64(op.maxCount),
+		getTimeout: op.getTimeout,
+
+		idleTimeout: op.idleTimeout,
+		maxLifetime: op.maxLifetime,
+
+		healthInterval: op.healthInterval,
+		healthService:  op.healthService,
+
+		maxInFlight: int64(op.maxInFlight),
+		notify:      make(chan struct{}, 1),
+
+		created: created,
+		emptied: newSignal(),
+
+		done: make(chan struct{}),
 	}
+	if c.created == nil {
+		c.created = newSignal()
+	}
+	c.factory = op.factory(
+		grpc.WithChainUnaryInterceptor(c.unaryInterceptor),
+		grpc.WithChainStreamInterceptor(c.streamInterceptor),
+	)
 
 	for i := 0; i < op.initCount; i++ {
-		conn, err := op.dial(addr)
+		conn, _, err := c.grow()
 		if err != nil {
+			if op.warmUp {
+				// 剩余的连接由后台继续建立
+				break
+			}
 			c.Close()
-			return nil, ErrInvalidFactory
+			return nil, err
 		}
-		c.conns <- &Conn{ClientConn: conn}
+		c.addIdle(conn)
+	}
+	if op.warmUp {
+		c.wg.Add(1)
+		go c.warmUp()
 	}
 
+	if c.healthInterval > 0 {
+		c.wg.Add(1)
+		go c.healthCheck()
+	}
+	if c.idleTimeout > 0 || c.maxLifetime > 0 {
+		c.wg.Add(1)
+		go c.janitor()
+	}
 	return c, nil
 }
 
+// Get 获取一个连接, 最多等待 getTimeout
 func (c *chanPool) Get() (*Conn, error) {
-	if c.conns == nil {
+	ctx := context.Background()
+	if c.getTimeout > 0 {
+		var cancelFunc context.CancelFunc
+		ctx, cancelFunc = context.WithTimeout(ctx, c.getTimeout)
+		defer cancelFunc()
+	}
+	return c.GetContext(ctx)
+}
+
+// GetContext 获取一个连接, 直到 ctx 超时或被取消
+func (c *chanPool) GetContext(ctx context.Context) (*Conn, error) {
+	if c.isClosed() {
 		return nil, ErrClosed
 	}
-	ctx, cancelFunc := context.WithTimeout(context.Background(), time.Duration(500)*time.Millisecond)
-	defer cancelFunc()
+	if c.maxInFlight > 0 {
+		return c.getShared(ctx)
+	}
+	for {
+		conn, err := c.acquire(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if conn != nil {
+			atomic.AddInt64(&c.inUse, 1)
+			conn.mu.Lock()
+			conn.checkedOut = true
+			conn.mu.Unlock()
+			return conn, nil
+		}
+		// 取到的连接不可用, 已被丢弃, 重新获取
+	}
+}
+
+// acquire 获取一个空闲连接或新建一个连接, 空闲连接不可用时返回 nil, nil
+func (c *chanPool) acquire(ctx context.Context) (*Conn, error) {
+	// 优先使用空闲连接
+	select {
+	case conn := <-c.conns:
+		return c.checkout(conn)
+	default:
+	}
+	// 没有空闲连接时, 在未达到 maxCount 前新建连接
+	if conn, ok, err := c.grow(); ok {
+		return conn, err
+	}
+	// 连接数已达到上限, 等待其他连接归还
+	start := time.Now()
+	defer func() {
+		c.stats.wait(time.Since(start))
+	}()
 	select {
 	case conn := <-c.conns:
-		if conn == nil {
-			// 无效连接
-			return c.new()
+		return c.checkout(conn)
+	case <-ctx.Done():
+		if ctx.Err() == context.DeadlineExceeded {
+			c.stats.timeout()
+			return nil, ErrConnectionLess
 		}
-		if conn.isUseless() {
-			err := conn.reset()
-			if err != nil {
-				return nil, err
-			}
+		return nil, ctx.Err()
+	}
+}
+
+func (c *chanPool) checkout(conn *Conn) (*Conn, error) {
+	if conn == nil {
+		// conns 已关闭
+		return nil, ErrClosed
+	}
+	if c.isClosed() {
+		c.retire(conn)
+		return nil, ErrClosed
+	}
+	if conn.isUseless() {
+		err := conn.reset()
+		if err != nil {
+			c.release()
+			return nil, c.dialFailed(err)
 		}
-		return conn, nil
-	case <-ctx.Done():
-		return nil, ErrConnectionLess
 	}
+	if !conn.healthy() || conn.expired(time.Now(), c.idleTimeout, c.maxLifetime) {
+		c.discard(conn)
+		return nil, nil
+	}
+	return conn, nil
 }
 
-func (c *chanPool) new() (*Conn, error) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	return newConn(c)
+// grow 在存活连接数未达到 maxCount 时新建一个连接, ok 为 false 表示已达到上限
+func (c *chanPool) grow() (conn *Conn, ok bool, err error) {
+	for {
+		n := atomic.LoadInt64(&c.active)
+		if n >= c.maxCount {
+			return nil, false, nil
+		}
+		if atomic.CompareAndSwapInt64(&c.active, n, n+1) {
+			break
+		}
+	}
+	conn, err = newConn(c)
+	if err != nil {
+		c.release()
+		return nil, true, c.dialFailed(err)
+	}
+	c.created.broadcast()
+	return conn, true, nil
 }
 
-func (c *chanPool) reset() {
-	c.mu.Lock()
+// release 一个连接被丢弃后, 让出它占用的名额
+func (c *chanPool) release() {
+	if atomic.AddInt64(&c.active, -1) == 0 {
+		c.emptied.broadcast()
+	}
+}
+
+// eachIdle 逐个取出当前的空闲连接交给 fn 处理, fn 返回 true 时放回连接池
+func (c *chanPool) eachIdle(fn func(conn *Conn) bool) {
+	if c.maxInFlight > 0 {
+		c.eachIdleShared(fn)
+		return
+	}
+	for i, n := 0, len(c.conns); i < n; i++ {
+		var conn *Conn
+		select {
+		case conn = <-c.conns:
+		default:
+			return
+		}
+		if fn(conn) {
+			c.conns <- conn
+		}
+	}
+}
+
+// giveBack 记录一个被取出的连接已归还
+func (c *chanPool) giveBack(useless bool) {
+	atomic.AddInt64(&c.inUse, -1)
+	if c.onReturn != nil {
+		c.onReturn(useless)
+	}
+}
+
+// discard 关闭并丢弃一个不可用或过期的连接
+func (c *chanPool) discard(conn *Conn) {
+	c.stats.evict()
+	c.retire(conn)
+}
 
+// retire 关闭一个连接并让出它占用的名额, 重复调用无效
+func (c *chanPool) retire(conn *Conn) {
+	if conn.shutdown() {
+		c.release()
+	}
+}
+
+// redial 关闭一个空闲连接并重新建立, 失败时丢弃该连接
+func (c *chanPool) redial(conn *Conn) bool {
+	c.stats.evict()
+	if err := conn.reset(); err != nil {
+		_ = c.dialFailed(err)
+		c.release()
+		return false
+	}
+	return true
+}
+
+func (c *chanPool) isClosed() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.closed
 }
 
+// Close 关闭连接池和所有空闲连接, 重复调用无效
+// 关闭后归还的连接会被直接关闭, Get 返回 ErrClosed
 func (c *chanPool) Close() {
+	if !c.markClosed() {
+		return
+	}
+	c.closeIdle()
+	c.closeShared()
+}
+
+// markClosed 标记连接池已关闭并停止后台任务, 已经关闭过时返回 false
+func (c *chanPool) markClosed() bool {
 	c.mu.Lock()
-	conns := c.conns
-	c.conns = nil
-	c.factory = nil
+	if c.closed {
+		c.mu.Unlock()
+		return false
+	}
+	c.closed = true
 	c.mu.Unlock()
 
-	if conns == nil {
-		return
-	}
+	// 后台任务会向 conns 中放回连接, 需要在关闭 conns 前退出
+	close(c.done)
+	c.wg.Wait()
+	return true
+}
 
-	close(conns)
-	for conn := range conns {
-		if conn != nil {
-			conn.Close()
-		}
+// closeIdle 关闭 conns 和其中的空闲连接
+func (c *chanPool) closeIdle() {
+	c.mu.Lock()
+	close(c.conns)
+	c.mu.Unlock()
+	for conn := range c.conns {
+		c.retire(conn)
 	}
 }
 
+// Len 空闲连接数, 共享模式下为没有调用方在使用的连接数
 func (c *chanPool) Len() int {
+	if c.maxInFlight > 0 {
+		return c.idleShared()
+	}
 	return len(c.conns)
 }
 
+// put 放回一个连接, 连接池已关闭时关闭该连接
 func (c *chanPool) put(conn *Conn) error {
-	c.mu.Lock()
-	ctx, cancelFunc := context.WithTimeout(context.Background(), time.Duration(500)*time.Millisecond)
-	defer cancelFunc()
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.closed {
+		c.retire(conn)
+		return nil
+	}
 	select {
 	case c.conns <- conn:
-		// valid op
-	case <-ctx.Done():
+		return nil
+	default:
+		// 存活连接数不超过 conns 的容量, 正常情况下不会发生
+		c.retire(conn)
 		return ErrConnectionFull
 	}
-	return nil
 }
@@ -0,0 +1,280 @@
+package grpcpool
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/connectivity"
+)
+
+func TestConnDoubleClose(t *testing.T) {
+	s := newBufServer(t)
+	p, err := NewPool("bufnet", WithDial(s.dial), WithInitCount(1), WithMaxCount(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	conn, err := p.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if err := conn.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if exp, act := 1, p.Len(); exp != act {
+		t.Errorf("Wrong idle count: %v != %v", act, exp)
+	}
+	if exp, act := 0, p.Stats().InUse; exp != act {
+		t.Errorf("Wrong in use count: %v != %v", act, exp)
+	}
+}
+
+func TestGetAfterClose(t *testing.T) {
+	s := newBufServer(t)
+	p, err := NewPool("bufnet", WithDial(s.dial), WithInitCount(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Close()
+	p.Close()
+
+	if _, err := p.Get(); err != ErrClosed {
+		t.Errorf("Get after Close: %v want %v", err, ErrClosed)
+	}
+	if exp, act := 0, p.Stats().Total; exp != act {
+		t.Errorf("Wrong total count: %v != %v", act, exp)
+	}
+}
+
+func TestReturnAfterClose(t *testing.T) {
+	s := newBufServer(t)
+	p, err := NewPool("bufnet", WithDial(s.dial), WithInitCount(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := p.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Close()
+
+	if err := conn.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := connectivity.Shutdown, conn.GetState(); exp != act {
+		t.Errorf("Wrong state of returned connection: %v != %v", act, exp)
+	}
+	if exp, act := 0, p.Stats().Total; exp != act {
+		t.Errorf("Wrong total count: %v != %v", act, exp)
+	}
+}
+
+func TestCloseWakesWaiters(t *testing.T) {
+	s := newBufServer(t)
+	p, err := NewPool("bufnet", WithDial(s.dial), WithInitCount(1), WithMaxCount(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Get(); err != nil {
+		t.Fatal(err)
+	}
+
+	errc := make(chan error)
+	go func() {
+		_, err := p.GetContext(context.Background())
+		errc <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+	p.Close()
+
+	select {
+	case err := <-errc:
+		if err != ErrClosed {
+			t.Errorf("GetContext woken by Close: %v want %v", err, ErrClosed)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("GetContext still blocked after Close")
+	}
+}
+
+func TestConcurrentGetCloseRace(t *testing.T) {
+	s := newBufServer(t)
+	p, err := NewPool("bufnet", WithDial(s.dial), WithInitCount(2), WithMaxCount(4),
+		WithGetTimeout(10*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	var unexpected int64
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				conn, err := p.Get()
+				switch err {
+				case nil:
+					if j%5 == 0 {
+						conn.MarkUseless()
+					}
+					_ = conn.Close()
+					_ = conn.Close()
+				case ErrClosed:
+					return
+				case ErrConnectionLess:
+				default:
+					atomic.AddInt64(&unexpected, 1)
+				}
+			}
+		}()
+	}
+	time.Sleep(20 * time.Millisecond)
+	p.Close()
+	wg.Wait()
+
+	if n := atomic.LoadInt64(&unexpected); n != 0 {
+		t.Errorf("%d unexpected errors", n)
+	}
+	if exp, act := 0, p.Stats().Total; exp != act {
+		t.Errorf("connections leaked after Close: %v != %v", act, exp)
+	}
+}
+
+// newExhaustedPool 只有一个连接且已被取出的连接池, 返回取出的连接
+func newExhaustedPool(t *testing.T, opts ...OptFn) (Pool, *Conn) {
+	t.Helper()
+	s := newBufServer(t)
+	p, err := NewPool("bufnet", append([]OptFn{WithDial(s.dial), WithInitCount(1), WithMaxCount(1)}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := p.Get()
+	if err != nil {
+		p.Close()
+		t.Fatal(err)
+	}
+	return p, conn
+}
+
+func TestGetContextCanceled(t *testing.T) {
+	p, conn := newExhaustedPool(t)
+	defer p.Close()
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+	if _, err := p.GetContext(ctx); err != context.Canceled {
+		t.Errorf("GetContext with canceled ctx: %v want %v", err, context.Canceled)
+	}
+	if _, err := p.GetContext(ctx); err != context.Canceled {
+		t.Errorf("GetContext with already canceled ctx: %v want %v", err, context.Canceled)
+	}
+}
+
+func TestGetContextDeadline(t *testing.T) {
+	p, conn := newExhaustedPool(t)
+	defer p.Close()
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := p.GetContext(ctx); err != ErrConnectionLess {
+		t.Errorf("GetContext past deadline: %v want %v", err, ErrConnectionLess)
+	}
+	if exp, act := int64(1), p.Stats().Timeouts; exp != act {
+		t.Errorf("Wrong timeout count: %v != %v", act, exp)
+	}
+}
+
+func TestGetWithoutTimeoutWaits(t *testing.T) {
+	p, conn := newExhaustedPool(t, WithGetTimeout(0))
+	defer p.Close()
+
+	got := make(chan error, 1)
+	go func() {
+		c, err := p.Get()
+		if err == nil {
+			_ = c.Close()
+		}
+		got <- err
+	}()
+	// 超过默认的 getTimeout 仍在等待
+	select {
+	case err := <-got:
+		t.Fatalf("Get without timeout returned early: %v", err)
+	case <-time.After(getTimeout + 100*time.Millisecond):
+	}
+
+	_ = conn.Close()
+	select {
+	case err := <-got:
+		if err != nil {
+			t.Errorf("Get after return: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Get was not woken by returned connection")
+	}
+}
+
+func TestGetGrowsUpToMaxCount(t *testing.T) {
+	s := newBufServer(t)
+	p, err := NewPool("bufnet", WithDial(s.dial), WithInitCount(1), WithMaxCount(3), WithGetTimeout(20*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	// 空闲连接用完后按需新建, 直到 maxCount
+	var conns []*Conn
+	for i := 0; i < 3; i++ {
+		conn, err := p.Get()
+		if err != nil {
+			t.Fatalf("Get %d: %v", i, err)
+		}
+		conns = append(conns, conn)
+		if exp, act := int64(i+1), s.dialCount(); exp != act {
+			t.Errorf("Wrong dial count after Get %d: %v != %v", i, act, exp)
+		}
+	}
+
+	// 达到上限后等待, 不再新建
+	if _, err := p.Get(); err != ErrConnectionLess {
+		t.Errorf("Get beyond max count: %v want %v", err, ErrConnectionLess)
+	}
+	if exp, act := int64(3), s.dialCount(); exp != act {
+		t.Errorf("Wrong dial count at max: %v != %v", act, exp)
+	}
+
+	got := make(chan error, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		conn, err := p.GetContext(ctx)
+		if err == nil {
+			_ = conn.Close()
+		}
+		got <- err
+	}()
+	select {
+	case err := <-got:
+		t.Fatalf("Get at max count returned without waiting: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	_ = conns[0].Close()
+	if err := <-got; err != nil {
+		t.Errorf("Get after return: %v", err)
+	}
+	for _, conn := range conns[1:] {
+		_ = conn.Close()
+	}
+	if exp, act := int64(3), s.dialCount(); exp != act {
+		t.Errorf("Wrong dial count after reuse: %v != %v", act, exp)
+	}
+}
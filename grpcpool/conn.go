@@ -2,6 +2,7 @@ package grpcpool
 
 import (
 	"sync"
+	"time"
 
 	"google.golang.org/grpc"
 )
@@ -12,10 +13,25 @@ type (
 		mu      sync.RWMutex
 		pool    *chanPool
 		useless bool
+		// checkedOut 连接已被取出尚未归还
+		checkedOut bool
+		// shut 底层连接已被连接池关闭
+		shut bool
+		// parent 共享模式下分配给调用方的连接所属的底层连接
+		parent *Conn
+		// inFlight 共享模式下底层连接当前分配给调用方的次数, 由 pool.smu 保护
+		inFlight int64
+		// createdAt 连接建立的时间, idleAt 连接最近一次放回连接池的时间
+		createdAt time.Time
+		idleAt    time.Time
+		// probeFailures 连续健康检查失败的次数, 只在检查空闲连接时访问
+		probeFailures int
 	}
 )
 
 func (c *Conn) isUseless() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
 	return c.useless
 }
 
@@ -30,30 +46,87 @@ func newConn(c *chanPool) (*Conn, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &Conn{ClientConn: conn, pool: c}, nil
+	return wrapConn(c, conn), nil
+}
+
+func wrapConn(c *chanPool, conn *grpc.ClientConn) *Conn {
+	now := time.Now()
+	wrapped := &Conn{ClientConn: conn, pool: c, createdAt: now, idleAt: now}
+	c.owners.Store(conn, wrapped)
+	return wrapped
+}
+
+// shutdown 关闭底层连接, 已经关闭过时返回 false
+func (c *Conn) shutdown() bool {
+	c.mu.Lock()
+	if c.shut {
+		c.mu.Unlock()
+		return false
+	}
+	c.shut = true
+	cc := c.ClientConn
+	c.mu.Unlock()
+	c.pool.owners.Delete(cc)
+	_ = cc.Close()
+	return true
+}
+
+// expired 判断连接是否超过了最大空闲时间或最大存活时间, 值 <= 0 表示不限制
+func (c *Conn) expired(now time.Time, idleTimeout, maxLifetime time.Duration) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if idleTimeout > 0 && now.Sub(c.idleAt) >= idleTimeout {
+		return true
+	}
+	if maxLifetime > 0 && now.Sub(c.createdAt) >= maxLifetime {
+		return true
+	}
+	return false
 }
 
 func (c *Conn) reset() error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	if c.ClientConn != nil {
+		c.pool.owners.Delete(c.ClientConn)
 		c.ClientConn.Close()
 	}
 	conn, err := c.pool.factory(c.pool.addr)
 	if err != nil {
 		return err
 	}
+	c.pool.owners.Store(conn, c)
 	c.useless = false
+	c.probeFailures = 0
 	c.ClientConn = conn
+	c.createdAt = time.Now()
+	c.idleAt = c.createdAt
 	return nil
 }
 
+// Close 将连接归还给连接池, 被标记为不可用的连接会被直接关闭, 重复调用无效
 func (c *Conn) Close() error {
 	if c == nil {
 		return nil
 	}
-	if c.isUseless() {
-		return c.ClientConn.Close()
+	c.mu.Lock()
+	if !c.checkedOut {
+		c.mu.Unlock()
+		return nil
+	}
+	c.checkedOut = false
+	useless := c.useless
+	c.idleAt = time.Now()
+	c.mu.Unlock()
+
+	c.pool.giveBack(useless)
+	if c.parent != nil {
+		c.pool.unlease(c.parent, useless)
+		return nil
+	}
+	if useless {
+		c.pool.retire(c)
+		return nil
 	}
 	// valid connection
 	return c.pool.put(c)
@@ -2,8 +2,10 @@ package grpcpool
 
 import (
 	"context"
+	"crypto/tls"
 	"time"
 
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/keepalive"
 
 	"google.golang.org/grpc/backoff"
@@ -26,43 +28,111 @@ const (
 	// keepAliveTime is the duration of time after which if the client doesn't see
 	// any activity it pings the server to see if the transport is still alive.
 	keepAliveTime = time.Duration(10) * time.Second
+	// getTimeout is the default duration Get waits for an available connection.
+	getTimeout = 500 * time.Millisecond
+	// healthCheckTimeout is the maximum duration of a single health check RPC.
+	healthCheckTimeout = time.Second
+	// healthCheckFailures is the number of consecutive failed health checks before a connection is redialed.
+	healthCheckFailures = 3
+	// maxFailures is the default number of consecutive failures before an endpoint is ejected.
+	maxFailures = 5
+	// ejectDuration is the default duration an endpoint stays ejected.
+	ejectDuration = 30 * time.Second
 )
 
 type (
 	Factory func(addr string) (*grpc.ClientConn, error)
 
 	Options struct {
+		// dial 自定义的 Factory, 设置后以下的拨号配置均不生效
 		dial Factory
 
-		initCount int
-		maxCount  int
+		tlsConfig      *tls.Config
+		perRPCCreds    []credentials.PerRPCCredentials
+		maxSendMsgSize int
+		maxRecvMsgSize int
+		keepalive      keepalive.ClientParameters
+		dialOptions    []grpc.DialOption
+
+		initCount  int
+		maxCount   int
+		getTimeout time.Duration
+
+		healthInterval time.Duration
+		healthService  string
+
+		idleTimeout time.Duration
+		maxLifetime time.Duration
+
+		balancer      Balancer
+		maxFailures   int
+		ejectDuration time.Duration
+
+		maxInFlight int
+
+		warmUp bool
 	}
 )
 
 var defaultOptions = Options{
-	dial:      Dial,
-	initCount: 5,
-	maxCount:  30,
+	maxSendMsgSize: maxSendMsgSize,
+	maxRecvMsgSize: maxRecvMsgSize,
+	keepalive: keepalive.ClientParameters{
+		Time:                keepAliveTime,
+		Timeout:             keepAliveTimeout,
+		PermitWithoutStream: true,
+	},
+
+	initCount:  5,
+	maxCount:   30,
+	getTimeout: getTimeout,
+
+	balancer:      RoundRobin,
+	maxFailures:   maxFailures,
+	ejectDuration: ejectDuration,
 }
 
+// Dial 使用默认配置建立连接
 func Dial(address string) (*grpc.ClientConn, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), dialTimeout)
-	defer cancel()
-	return grpc.DialContext(ctx, address,
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
-		grpc.WithConnectParams(grpc.ConnectParams{Backoff: backoff.DefaultConfig}),
-		grpc.WithDefaultCallOptions(grpc.MaxCallSendMsgSize(maxSendMsgSize)),
-		grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(maxRecvMsgSize)),
-		grpc.WithKeepaliveParams(keepalive.ClientParameters{
-			Time:                keepAliveTime,
-			Timeout:             keepAliveTimeout,
-			PermitWithoutStream: true,
-		}))
+	return defaultOptions.factory()(address)
+}
 
+// factory 返回建立连接的 Factory, 未通过 WithDial 自定义时根据拨号配置和 extra 生成
+func (o *Options) factory(extra ...grpc.DialOption) Factory {
+	if o.dial != nil {
+		return o.dial
+	}
+	dialOptions := append(extra, o.buildDialOptions()...)
+	return func(addr string) (*grpc.ClientConn, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), dialTimeout)
+		defer cancel()
+		return grpc.DialContext(ctx, addr, dialOptions...)
+	}
+}
+
+func (o *Options) buildDialOptions() []grpc.DialOption {
+	creds := insecure.NewCredentials()
+	if o.tlsConfig != nil {
+		creds = credentials.NewTLS(o.tlsConfig)
+	}
+	dialOptions := []grpc.DialOption{
+		grpc.WithTransportCredentials(creds),
+		grpc.WithConnectParams(grpc.ConnectParams{Backoff: backoff.DefaultConfig}),
+		grpc.WithDefaultCallOptions(grpc.MaxCallSendMsgSize(o.maxSendMsgSize)),
+		grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(o.maxRecvMsgSize)),
+		grpc.WithKeepaliveParams(o.keepalive),
+	}
+	for _, c := range o.perRPCCreds {
+		dialOptions = append(dialOptions, grpc.WithPerRPCCredentials(c))
+	}
+	// 自定义的 DialOption 放在最后, 可以覆盖前面的配置
+	return append(dialOptions, o.dialOptions...)
 }
 
 type OptFn = func(option *Options)
 
+// WithDial 自定义建立连接的 Factory, 会忽略 WithTLSConfig 等拨号配置
+// 自定义的 Factory 建立的连接不会安装自动标记不可用连接的拦截器
 func WithDial(dial Factory) OptFn {
 	return func(option *Options) {
 		option.dial = dial
@@ -80,3 +150,100 @@ func WithMaxCount(c int) OptFn {
 		option.maxCount = c
 	}
 }
+
+// WithTLSConfig 使用 TLS 建立连接, 配置 Certificates 即为 mTLS
+func WithTLSConfig(config *tls.Config) OptFn {
+	return func(option *Options) {
+		option.tlsConfig = config
+	}
+}
+
+// WithPerRPCCredentials 为每个请求附加认证信息, 例如 token
+func WithPerRPCCredentials(creds credentials.PerRPCCredentials) OptFn {
+	return func(option *Options) {
+		option.perRPCCreds = append(option.perRPCCreds, creds)
+	}
+}
+
+// WithMaxMsgSize 设置发送和接收消息的最大字节数
+func WithMaxMsgSize(send, recv int) OptFn {
+	return func(option *Options) {
+		option.maxSendMsgSize = send
+		option.maxRecvMsgSize = recv
+	}
+}
+
+// WithKeepalive 设置客户端的 keepalive 参数
+func WithKeepalive(params keepalive.ClientParameters) OptFn {
+	return func(option *Options) {
+		option.keepalive = params
+	}
+}
+
+// WithDialOptions 追加自定义的 grpc.DialOption, 优先级高于其他拨号配置
+func WithDialOptions(opts ...grpc.DialOption) OptFn {
+	return func(option *Options) {
+		option.dialOptions = append(option.dialOptions, opts...)
+	}
+}
+
+// WithGetTimeout 设置 Get 等待可用连接的最长时间, t <= 0 时一直等待
+func WithGetTimeout(t time.Duration) OptFn {
+	return func(option *Options) {
+		option.getTimeout = t
+	}
+}
+
+// WithHealthCheck 每隔 interval 通过 grpc.health.v1 检查空闲连接, 并重建不可用的连接
+// service 为要检查的服务名, 为空时检查服务端整体状态
+func WithHealthCheck(interval time.Duration, service string) OptFn {
+	return func(option *Options) {
+		option.healthInterval = interval
+		option.healthService = service
+	}
+}
+
+// WithIdleTimeout 设置连接在连接池中的最长空闲时间, 超过后被关闭并重建
+func WithIdleTimeout(t time.Duration) OptFn {
+	return func(option *Options) {
+		option.idleTimeout = t
+	}
+}
+
+// WithMaxLifetime 设置连接的最长存活时间, 超过后被关闭并重建
+func WithMaxLifetime(t time.Duration) OptFn {
+	return func(option *Options) {
+		option.maxLifetime = t
+	}
+}
+
+// WithBalancer 设置多地址连接池选择节点的策略
+func WithBalancer(b Balancer) OptFn {
+	return func(option *Options) {
+		option.balancer = b
+	}
+}
+
+// WithOutlierDetection 节点连续失败 maxFailures 次后摘除 ejectDuration 时间, maxFailures <= 0 时不摘除
+func WithOutlierDetection(maxFailures int, ejectDuration time.Duration) OptFn {
+	return func(option *Options) {
+		option.maxFailures = maxFailures
+		option.ejectDuration = ejectDuration
+	}
+}
+
+// WithMaxInFlight 开启共享模式, 每个连接最多同时分配给 n 个调用方, Get 返回当前负载最小的连接
+// 调用方使用完毕后仍需调用 Conn.Close, n 通常不超过服务端的 MaxConcurrentStreams
+func WithMaxInFlight(n int) OptFn {
+	return func(option *Options) {
+		option.maxInFlight = n
+	}
+}
+
+// WithWarmUp 初始连接建立失败时不返回错误, 而是在后台按退避间隔继续建立, 直到达到 initCount
+// 可以通过 Pool.Ready 等待连接建立
+func WithWarmUp() OptFn {
+	return func(option *Options) {
+		option.warmUp = true
+	}
+}
@@ -0,0 +1,55 @@
+package grpcpool
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type tokenCreds string
+
+func (t tokenCreds) GetRequestMetadata(context.Context, ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer " + string(t)}, nil
+}
+
+func (t tokenCreds) RequireTransportSecurity() bool {
+	return false
+}
+
+func TestDialOptionsFeedDefaultFactory(t *testing.T) {
+	auth := func(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		if v := md.Get("authorization"); len(v) != 1 || v[0] != "Bearer secret" {
+			return nil, status.Error(codes.Unauthenticated, "missing token")
+		}
+		return handler(ctx, req)
+	}
+	s := newBufServer(t, grpc.UnaryInterceptor(auth))
+
+	p, err := NewPool("bufnet", WithInitCount(1),
+		WithPerRPCCredentials(tokenCreds("secret")),
+		WithMaxMsgSize(1<<20, 1<<20),
+		WithDialOptions(s.dialer()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	conn, err := p.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	resp, err := grpc_health_v1.NewHealthClient(conn).Check(context.Background(), &grpc_health_v1.HealthCheckRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := grpc_health_v1.HealthCheckResponse_SERVING, resp.GetStatus(); exp != act {
+		t.Errorf("Wrong status: %v != %v", act, exp)
+	}
+}
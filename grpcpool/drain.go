@@ -0,0 +1,96 @@
+package grpcpool
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// Drain 停止分配连接并关闭空闲连接, 等待已取出的连接归还后关闭
+// ctx 结束时强制关闭仍未归还的连接, 返回强制关闭的连接数和 ctx.Err()
+func (c *chanPool) Drain(ctx context.Context) (int, error) {
+	if !c.markClosed() {
+		return 0, nil
+	}
+	c.closeIdle()
+	c.closeIdleShared()
+	for {
+		// 先取得通知 channel 再检查, 避免错过检查之后的广播
+		wait := c.emptied.wait()
+		if atomic.LoadInt64(&c.active) <= 0 {
+			return 0, nil
+		}
+		select {
+		case <-wait:
+		case <-ctx.Done():
+			return c.forceClose(), ctx.Err()
+		}
+	}
+}
+
+// closeIdleShared 关闭没有调用方在使用的共享连接, 其余的在最后一个调用方归还时关闭
+func (c *chanPool) closeIdleShared() {
+	var idle []*Conn
+	c.smu.Lock()
+	for i := 0; i < len(c.shared); {
+		if conn := c.shared[i]; conn.inFlight == 0 {
+			idle = append(idle, conn)
+			c.removeShared(conn)
+			continue
+		}
+		i++
+	}
+	c.smu.Unlock()
+	for _, conn := range idle {
+		c.retire(conn)
+	}
+}
+
+// forceClose 关闭所有仍存活的连接, 返回关闭的连接数
+func (c *chanPool) forceClose() int {
+	c.smu.Lock()
+	c.shared = nil
+	c.smu.Unlock()
+
+	var n int
+	c.owners.Range(func(_, value interface{}) bool {
+		conn := value.(*Conn)
+		if conn.shutdown() {
+			c.release()
+			n++
+		}
+		return true
+	})
+	return n
+}
+
+// Drain 并发地排空所有节点, 返回强制关闭的连接总数
+func (m *multiPool) Drain(ctx context.Context) (int, error) {
+	// 与 Close 相同, 开始排空后 Ready 立即返回 ErrClosed
+	m.closeOnce.Do(func() {
+		close(m.done)
+	})
+	var (
+		wg     sync.WaitGroup
+		forced int64
+		mu     sync.Mutex
+		first  error
+	)
+	for _, e := range m.endpoints {
+		wg.Add(1)
+		go func(p *chanPool) {
+			defer wg.Done()
+			n, err := p.Drain(ctx)
+			atomic.AddInt64(&forced, int64(n))
+			if err != nil {
+				mu.Lock()
+				if first == nil {
+					first = err
+				}
+				mu.Unlock()
+			}
+		}(e.pool)
+	}
+	wg.Wait()
+	return int(forced), first
+}
@@ -0,0 +1,122 @@
+package grpcpool
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/connectivity"
+)
+
+type drainResult struct {
+	forced int
+	err    error
+}
+
+func drainAsync(p Pool, timeout time.Duration) <-chan drainResult {
+	res := make(chan drainResult, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		n, err := p.Drain(ctx)
+		res <- drainResult{n, err}
+	}()
+	return res
+}
+
+func TestDrainWaitsForCheckedOutConnections(t *testing.T) {
+	for _, maxInFlight := range []int{0, 2} {
+		s := newBufServer(t)
+		p, err := NewPool("bufnet", WithDial(s.dial), WithInitCount(2), WithMaxInFlight(maxInFlight))
+		if err != nil {
+			t.Fatal(err)
+		}
+		a, err := p.Get()
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := p.Get()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		res := drainAsync(p, 2*time.Second)
+		time.Sleep(20 * time.Millisecond)
+		if _, err := p.Get(); err != ErrClosed {
+			t.Errorf("Get while draining: %v want %v", err, ErrClosed)
+		}
+		_ = a.Close()
+		select {
+		case <-res:
+			t.Fatalf("Drain returned before all connections were returned (maxInFlight %d)", maxInFlight)
+		case <-time.After(20 * time.Millisecond):
+		}
+		_ = b.Close()
+
+		r := <-res
+		if r.forced != 0 || r.err != nil {
+			t.Errorf("Drain = %d, %v want 0, nil (maxInFlight %d)", r.forced, r.err, maxInFlight)
+		}
+		if exp, act := connectivity.Shutdown, b.GetState(); exp != act {
+			t.Errorf("Wrong state of returned connection: %v != %v", act, exp)
+		}
+		if exp, act := 0, p.Stats().Total; exp != act {
+			t.Errorf("Wrong total count: %v != %v", act, exp)
+		}
+	}
+}
+
+func TestDrainForceClosesOnDeadline(t *testing.T) {
+	s := newBufServer(t)
+	p, err := NewPool("bufnet", WithDial(s.dial), WithInitCount(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, _ := p.Get()
+	b, _ := p.Get()
+
+	r := <-drainAsync(p, 30*time.Millisecond)
+	if r.forced != 2 || r.err != context.DeadlineExceeded {
+		t.Errorf("Drain = %d, %v want 2, %v", r.forced, r.err, context.DeadlineExceeded)
+	}
+	for _, conn := range []*Conn{a, b} {
+		if exp, act := connectivity.Shutdown, conn.GetState(); exp != act {
+			t.Errorf("Wrong state of forcibly closed connection: %v != %v", act, exp)
+		}
+		_ = conn.Close()
+	}
+	if exp, act := 0, p.Stats().Total; exp != act {
+		t.Errorf("Wrong total count: %v != %v", act, exp)
+	}
+	if n, err := p.Drain(context.Background()); n != 0 || err != nil {
+		t.Errorf("second Drain = %d, %v want 0, nil", n, err)
+	}
+}
+
+func TestMultiPoolReadyReturnsAfterDrain(t *testing.T) {
+	s := newBufServer(t)
+	p, err := NewMultiPool([]string{"a", "b"}, WithDial(flakyDial(s, -1)), WithInitCount(1), WithWarmUp())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ready := make(chan error, 1)
+	go func() {
+		ready <- p.Ready(context.Background(), 1)
+	}()
+	time.Sleep(20 * time.Millisecond)
+	if _, err := p.Drain(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-ready:
+		if err != ErrClosed {
+			t.Errorf("Ready after Drain: %v want %v", err, ErrClosed)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Ready did not return after Drain")
+	}
+	if err := p.Ready(context.Background(), 1); err != ErrClosed {
+		t.Errorf("Ready on drained pool: %v want %v", err, ErrClosed)
+	}
+}
@@ -0,0 +1,77 @@
+package grpcpool
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+// healthy 根据连接状态判断连接是否可用, TRANSIENT_FAILURE 和 SHUTDOWN 状态的连接不可用
+func (c *Conn) healthy() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	switch c.ClientConn.GetState() {
+	case connectivity.TransientFailure, connectivity.Shutdown:
+		return false
+	default:
+		return true
+	}
+}
+
+// probe 按 grpc.health.v1 协议检查连接, 返回 false 表示需要重建连接
+// 服务端没有实现健康检查时视为可用, 明确返回 NOT_SERVING 时立即重建
+// 其他错误连续 healthCheckFailures 次后才重建, 避免一次失败就重建所有连接
+func (c *Conn) probe(service string, timeout time.Duration) bool {
+	if !c.healthy() {
+		return false
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	resp, err := grpc_health_v1.NewHealthClient(c.ClientConn).Check(ctx, &grpc_health_v1.HealthCheckRequest{Service: service})
+	switch {
+	case status.Code(err) == codes.Unimplemented:
+	case err != nil:
+		c.probeFailures++
+		return c.probeFailures < healthCheckFailures
+	case resp.GetStatus() == grpc_health_v1.HealthCheckResponse_NOT_SERVING:
+		return false
+	case resp.GetStatus() != grpc_health_v1.HealthCheckResponse_SERVING:
+		c.probeFailures++
+		return c.probeFailures < healthCheckFailures
+	}
+	c.probeFailures = 0
+	return true
+}
+
+// healthCheck 定时检查空闲连接, 重建不可用的连接
+func (c *chanPool) healthCheck() {
+	defer c.wg.Done()
+	ticker := time.NewTicker(c.healthInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-ticker.C:
+			c.checkIdle()
+		}
+	}
+}
+
+// checkIdle 检查当前的空闲连接, 检查期间该连接不会被 Get 取到
+func (c *chanPool) checkIdle() {
+	timeout := c.healthInterval
+	if timeout > healthCheckTimeout {
+		timeout = healthCheckTimeout
+	}
+	c.eachIdle(func(conn *Conn) bool {
+		if conn.probe(c.healthService, timeout) {
+			return true
+		}
+		return c.redial(conn)
+	})
+}
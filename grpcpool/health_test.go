@@ -0,0 +1,160 @@
+package grpcpool
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+type bufServer struct {
+	lis    *bufconn.Listener
+	srv    *grpc.Server
+	health *health.Server
+	dials  int64
+}
+
+func newBufServer(t *testing.T, opts ...grpc.ServerOption) *bufServer {
+	t.Helper()
+	s := newBareBufServer(t, opts...)
+	s.health = health.NewServer()
+	grpc_health_v1.RegisterHealthServer(s.srv, s.health)
+	return s
+}
+
+// newBareBufServer 没有注册健康检查服务的 bufServer
+func newBareBufServer(t *testing.T, opts ...grpc.ServerOption) *bufServer {
+	t.Helper()
+	s := &bufServer{
+		lis: bufconn.Listen(1 << 20),
+		srv: grpc.NewServer(opts...),
+	}
+	go func() {
+		_ = s.srv.Serve(s.lis)
+	}()
+	t.Cleanup(s.srv.Stop)
+	return s
+}
+
+func (s *bufServer) dial(addr string) (*grpc.ClientConn, error) {
+	atomic.AddInt64(&s.dials, 1)
+	return grpc.Dial(addr, s.dialer(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+}
+
+func (s *bufServer) dialer() grpc.DialOption {
+	return grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+		return s.lis.DialContext(ctx)
+	})
+}
+
+func (s *bufServer) dialCount() int64 {
+	return atomic.LoadInt64(&s.dials)
+}
+
+func TestGetSkipsShutdownConnection(t *testing.T) {
+	s := newBufServer(t)
+	p, err := NewPool("bufnet", WithDial(s.dial), WithInitCount(1), WithMaxCount(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	c := p.(*chanPool)
+	idle := <-c.conns
+	broken := idle.ClientConn
+	_ = broken.Close()
+	c.conns <- idle
+
+	conn, err := p.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conn.ClientConn == broken {
+		t.Errorf("Get returned a connection in SHUTDOWN state")
+	}
+	if exp, act := int64(2), s.dialCount(); exp != act {
+		t.Errorf("Wrong dial count: %v != %v", act, exp)
+	}
+	if exp, act := int64(1), atomic.LoadInt64(&c.active); exp != act {
+		t.Errorf("Wrong active count: %v != %v", act, exp)
+	}
+}
+
+func TestHealthCheckRedialsUnhealthyConnection(t *testing.T) {
+	s := newBufServer(t)
+	s.health.SetServingStatus("", grpc_health_v1.HealthCheckResponse_NOT_SERVING)
+	p, err := NewPool("bufnet", WithDial(s.dial), WithInitCount(2), WithHealthCheck(10*time.Millisecond, ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for s.dialCount() <= 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("unhealthy connections were not redialed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	s.health.SetServingStatus("", grpc_health_v1.HealthCheckResponse_SERVING)
+	time.Sleep(50 * time.Millisecond)
+	dials := s.dialCount()
+	time.Sleep(50 * time.Millisecond)
+	if exp, act := dials, s.dialCount(); exp != act {
+		t.Errorf("healthy connections were redialed: %v != %v", act, exp)
+	}
+	if exp, act := int64(2), atomic.LoadInt64(&p.(*chanPool).active); exp != act {
+		t.Errorf("Wrong active count: %v != %v", act, exp)
+	}
+}
+
+func TestHealthCheckWithoutHealthService(t *testing.T) {
+	s := newBareBufServer(t)
+	p, err := NewPool("bufnet", WithDial(s.dial), WithInitCount(2), WithHealthCheck(10*time.Millisecond, ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	// 服务端没有实现健康检查时视为可用, 不重建连接
+	time.Sleep(200 * time.Millisecond)
+	if exp, act := int64(2), s.dialCount(); exp != act {
+		t.Errorf("connections were redialed: %v != %v", act, exp)
+	}
+}
+
+func TestProbeToleratesTransientFailures(t *testing.T) {
+	s := newBufServer(t)
+	p, err := NewPool("bufnet", WithDial(s.dial), WithInitCount(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	conn := <-p.(*chanPool).conns
+	// 未知的服务返回 NotFound, 连续失败 healthCheckFailures 次才需要重建
+	for i := 1; i < healthCheckFailures; i++ {
+		if !conn.probe("missing", time.Second) {
+			t.Fatalf("probe failure %d should not redial", i)
+		}
+	}
+	if conn.probe("missing", time.Second) {
+		t.Errorf("probe after %d failures should redial", healthCheckFailures)
+	}
+
+	// 成功后重新计数
+	conn.probeFailures = 0
+	conn.probe("missing", time.Second)
+	if !conn.probe("", time.Second) || conn.probeFailures != 0 {
+		t.Errorf("serving probe should reset failures, got %d", conn.probeFailures)
+	}
+	p.(*chanPool).conns <- conn
+}
@@ -0,0 +1,74 @@
+package grpcpool
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"syscall"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// isTransportError 判断 RPC 错误是否说明底层连接已不可用
+func isTransportError(err error) bool {
+	if err == nil || err == io.EOF {
+		return false
+	}
+	if status.Code(err) == codes.Unavailable {
+		return true
+	}
+	if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.EPIPE) {
+		return true
+	}
+	msg := err.Error()
+	return strings.Contains(msg, "connection reset by peer") || strings.Contains(msg, "broken pipe")
+}
+
+// markUseless 将 cc 所属的连接标记为不可用, 归还时会被关闭而不是放回连接池
+func (c *chanPool) markUseless(cc *grpc.ClientConn, err error) {
+	if !isTransportError(err) {
+		return
+	}
+	if conn, ok := c.owners.Load(cc); ok {
+		conn.(*Conn).MarkUseless()
+	}
+}
+
+func (c *chanPool) unaryInterceptor(ctx context.Context, method string, req, reply interface{},
+	cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	err := invoker(ctx, method, req, reply, cc, opts...)
+	c.markUseless(cc, err)
+	return err
+}
+
+func (c *chanPool) streamInterceptor(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn,
+	method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	stream, err := streamer(ctx, desc, cc, method, opts...)
+	if err != nil {
+		c.markUseless(cc, err)
+		return nil, err
+	}
+	return &monitoredStream{ClientStream: stream, pool: c, cc: cc}, nil
+}
+
+// monitoredStream 检查流上收发消息的错误
+type monitoredStream struct {
+	grpc.ClientStream
+	pool *chanPool
+	cc   *grpc.ClientConn
+}
+
+func (s *monitoredStream) SendMsg(m interface{}) error {
+	err := s.ClientStream.SendMsg(m)
+	s.pool.markUseless(s.cc, err)
+	return err
+}
+
+func (s *monitoredStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	s.pool.markUseless(s.cc, err)
+	return err
+}
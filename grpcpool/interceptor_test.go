@@ -0,0 +1,68 @@
+package grpcpool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"syscall"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+func TestIsTransportError(t *testing.T) {
+	cases := []struct {
+		err  error
+		want bool
+	}{
+		{nil, false},
+		{io.EOF, false},
+		{status.Error(codes.NotFound, "not found"), false},
+		{status.Error(codes.DeadlineExceeded, "timeout"), false},
+		{status.Error(codes.Unavailable, "transport is closing"), true},
+		{fmt.Errorf("write: %w", syscall.ECONNRESET), true},
+		{errors.New("read tcp: connection reset by peer"), true},
+	}
+	for _, tc := range cases {
+		if got := isTransportError(tc.err); got != tc.want {
+			t.Errorf("isTransportError(%v) = %v want %v", tc.err, got, tc.want)
+		}
+	}
+}
+
+func TestInterceptorMarksConnectionUseless(t *testing.T) {
+	code := codes.NotFound
+	fail := func(context.Context, interface{}, *grpc.UnaryServerInfo, grpc.UnaryHandler) (interface{}, error) {
+		return nil, status.Error(code, "failed")
+	}
+	s := newBufServer(t, grpc.UnaryInterceptor(fail))
+	p, err := NewPool("bufnet", WithInitCount(1), WithMaxCount(1), WithDialOptions(s.dialer()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	check := func() {
+		conn, err := p.Get()
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = grpc_health_v1.NewHealthClient(conn).Check(context.Background(), &grpc_health_v1.HealthCheckRequest{})
+		_ = conn.Close()
+	}
+
+	check()
+	if exp, act := 1, p.Len(); exp != act {
+		t.Errorf("connection discarded on application error: %v != %v", act, exp)
+	}
+
+	code = codes.Unavailable
+	check()
+	if stats := p.Stats(); stats.Total != 0 || stats.Idle != 0 {
+		t.Errorf("connection was not discarded on Unavailable: %+v", stats)
+	}
+}
@@ -0,0 +1,48 @@
+package grpcpool
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// janitorInterval 清理任务的执行间隔, 取空闲时间和存活时间中较小值的一半
+func (c *chanPool) janitorInterval() time.Duration {
+	interval := c.idleTimeout
+	if interval <= 0 || (c.maxLifetime > 0 && c.maxLifetime < interval) {
+		interval = c.maxLifetime
+	}
+	interval /= 2
+	if interval < time.Millisecond {
+		interval = time.Millisecond
+	}
+	return interval
+}
+
+// janitor 定时关闭超过空闲时间或存活时间的连接
+func (c *chanPool) janitor() {
+	defer c.wg.Done()
+	ticker := time.NewTicker(c.janitorInterval())
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.done:
+			return
+		case now := <-ticker.C:
+			c.evictExpired(now)
+		}
+	}
+}
+
+// evictExpired 关闭过期的空闲连接, 存活连接数不超过 initCount 时重建被关闭的连接
+func (c *chanPool) evictExpired(now time.Time) {
+	c.eachIdle(func(conn *Conn) bool {
+		if !conn.expired(now, c.idleTimeout, c.maxLifetime) {
+			return true
+		}
+		if atomic.LoadInt64(&c.active) > c.initCount {
+			c.discard(conn)
+			return false
+		}
+		return c.redial(conn)
+	})
+}
@@ -0,0 +1,78 @@
+package grpcpool
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGetReplacesExpiredConnection(t *testing.T) {
+	s := newBufServer(t)
+	p, err := NewPool("bufnet", WithDial(s.dial), WithInitCount(1), WithMaxCount(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	c := p.(*chanPool)
+	c.maxLifetime = 20 * time.Millisecond
+	idle := <-c.conns
+	stale := idle.ClientConn
+	c.conns <- idle
+	time.Sleep(30 * time.Millisecond)
+
+	conn, err := p.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conn.ClientConn == stale {
+		t.Errorf("Get returned an expired connection")
+	}
+	if exp, act := int64(1), atomic.LoadInt64(&c.active); exp != act {
+		t.Errorf("Wrong active count: %v != %v", act, exp)
+	}
+}
+
+func TestJanitorReplacesIdleConnections(t *testing.T) {
+	s := newBufServer(t)
+	p, err := NewPool("bufnet", WithDial(s.dial), WithInitCount(2), WithIdleTimeout(20*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for s.dialCount() <= 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("idle connections were not replaced")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if exp, act := int64(2), atomic.LoadInt64(&p.(*chanPool).active); exp != act {
+		t.Errorf("Wrong active count: %v != %v", act, exp)
+	}
+
+	p.Close()
+	dials := s.dialCount()
+	time.Sleep(50 * time.Millisecond)
+	if exp, act := dials, s.dialCount(); exp != act {
+		t.Errorf("janitor still running after Close: %v != %v", act, exp)
+	}
+}
+
+func TestJanitorInterval(t *testing.T) {
+	cases := []struct {
+		idle, lifetime, interval time.Duration
+	}{
+		{time.Minute, 0, 30 * time.Second},
+		{0, time.Minute, 30 * time.Second},
+		{time.Minute, 10 * time.Second, 5 * time.Second},
+		{10 * time.Second, time.Minute, 5 * time.Second},
+		{time.Microsecond, 0, time.Millisecond},
+	}
+	for _, tc := range cases {
+		c := &chanPool{idleTimeout: tc.idle, maxLifetime: tc.lifetime}
+		if exp, act := tc.interval, c.janitorInterval(); exp != act {
+			t.Errorf("janitorInterval(%v, %v) = %v want %v", tc.idle, tc.lifetime, act, exp)
+		}
+	}
+}
@@ -0,0 +1,244 @@
+package grpcpool
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type (
+	// Manager 按目标地址管理多个连接池, 第一次使用某个地址时创建对应的连接池
+	Manager struct {
+		mu     sync.Mutex
+		pools  map[string]*managedPool
+		closed bool
+
+		defaults []OptFn
+		targets  map[string][]OptFn
+		idleTTL  time.Duration
+
+		done chan struct{}
+		wg   sync.WaitGroup
+	}
+
+	managedPool struct {
+		Pool
+		m      *Manager
+		target string
+		// lastUsed 最近一次获取连接的时间, UnixNano
+		lastUsed int64
+	}
+
+	ManagerOptFn = func(m *Manager)
+)
+
+// NewManager 创建连接池管理器
+func NewManager(opts ...ManagerOptFn) *Manager {
+	m := &Manager{
+		pools:   make(map[string]*managedPool),
+		targets: make(map[string][]OptFn),
+		done:    make(chan struct{}),
+	}
+	for _, fn := range opts {
+		fn(m)
+	}
+	if m.idleTTL > 0 {
+		m.wg.Add(1)
+		go m.reap()
+	}
+	return m
+}
+
+// WithDefaultOptions 所有连接池共用的配置
+func WithDefaultOptions(opts ...OptFn) ManagerOptFn {
+	return func(m *Manager) {
+		m.defaults = append(m.defaults, opts...)
+	}
+}
+
+// WithTargetOptions 单个地址的配置, 覆盖 WithDefaultOptions 中的同名配置
+func WithTargetOptions(target string, opts ...OptFn) ManagerOptFn {
+	return func(m *Manager) {
+		m.targets[target] = append(m.targets[target], opts...)
+	}
+}
+
+// WithPoolIdleTTL 连接池超过 ttl 没有获取连接, 且没有被取出的连接时关闭该连接池
+func WithPoolIdleTTL(ttl time.Duration) ManagerOptFn {
+	return func(m *Manager) {
+		m.idleTTL = ttl
+	}
+}
+
+// Pool 返回 target 对应的连接池, 不存在时创建
+// 连接池因空闲被关闭后, 返回值的 Get 和 GetContext 通过 Manager 重新创建连接池, 可以长期持有
+// 其他方法仍作用于已关闭的连接池, 例如 Ready 返回 ErrClosed, 需要时应重新调用 Pool
+func (m *Manager) Pool(target string) (Pool, error) {
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return nil, ErrClosed
+	}
+	if mp, ok := m.pools[target]; ok {
+		m.mu.Unlock()
+		return mp, nil
+	}
+	opts := append(append([]OptFn{}, m.defaults...), m.targets[target]...)
+	m.mu.Unlock()
+
+	// 建立连接可能较慢, 不持有锁
+	p, err := NewPool(target, opts...)
+	if err != nil {
+		return nil, err
+	}
+	mp := &managedPool{Pool: p, m: m, target: target, lastUsed: time.Now().UnixNano()}
+
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		p.Close()
+		return nil, ErrClosed
+	}
+	if exist, ok := m.pools[target]; ok {
+		// 其他调用方已经创建
+		m.mu.Unlock()
+		p.Close()
+		return exist, nil
+	}
+	m.pools[target] = mp
+	m.mu.Unlock()
+	return mp, nil
+}
+
+// Get 从 target 对应的连接池获取一个连接
+func (m *Manager) Get(target string) (*Conn, error) {
+	return m.get(target, func(p Pool) (*Conn, error) {
+		return p.Get()
+	})
+}
+
+// GetContext 从 target 对应的连接池获取一个连接, 直到 ctx 超时或被取消
+func (m *Manager) GetContext(ctx context.Context, target string) (*Conn, error) {
+	return m.get(target, func(p Pool) (*Conn, error) {
+		return p.GetContext(ctx)
+	})
+}
+
+func (m *Manager) get(target string, get func(p Pool) (*Conn, error)) (*Conn, error) {
+	for {
+		p, err := m.Pool(target)
+		if err != nil {
+			return nil, err
+		}
+		mp := p.(*managedPool)
+		atomic.StoreInt64(&mp.lastUsed, time.Now().UnixNano())
+		conn, err := get(mp.Pool)
+		if err == ErrClosed && m.remove(target, mp) {
+			// 连接池刚好因空闲被关闭, 重新创建
+			continue
+		}
+		return conn, err
+	}
+}
+
+// Get 记录使用时间, 调用方直接持有 Manager.Pool 返回的连接池时也不会因空闲被关闭
+// 连接池已经因空闲被关闭时, 通过 Manager 重新获取
+func (mp *managedPool) Get() (*Conn, error) {
+	return mp.get(func(p Pool) (*Conn, error) {
+		return p.Get()
+	})
+}
+
+func (mp *managedPool) GetContext(ctx context.Context) (*Conn, error) {
+	return mp.get(func(p Pool) (*Conn, error) {
+		return p.GetContext(ctx)
+	})
+}
+
+func (mp *managedPool) get(get func(p Pool) (*Conn, error)) (*Conn, error) {
+	atomic.StoreInt64(&mp.lastUsed, time.Now().UnixNano())
+	conn, err := get(mp.Pool)
+	if err == ErrClosed && mp.m.remove(mp.target, mp) {
+		return mp.m.get(mp.target, get)
+	}
+	return conn, err
+}
+
+// remove 移除 target 对应的连接池 mp, 返回 mp 是否仍在管理中
+func (m *Manager) remove(target string, mp *managedPool) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.pools[target] != mp {
+		return !m.closed
+	}
+	delete(m.pools, target)
+	return true
+}
+
+// Len 当前管理的连接池数量
+func (m *Manager) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.pools)
+}
+
+// reapInterval 空闲检查的间隔, 取 idleTTL 的一半
+func (m *Manager) reapInterval() time.Duration {
+	interval := m.idleTTL / 2
+	if interval < time.Millisecond {
+		interval = time.Millisecond
+	}
+	return interval
+}
+
+// reap 定时关闭空闲的连接池
+func (m *Manager) reap() {
+	defer m.wg.Done()
+	ticker := time.NewTicker(m.reapInterval())
+	defer ticker.Stop()
+	for {
+		select {
+		case <-m.done:
+			return
+		case now := <-ticker.C:
+			m.closeIdle(now)
+		}
+	}
+}
+
+func (m *Manager) closeIdle(now time.Time) {
+	var idle []Pool
+	m.mu.Lock()
+	for target, mp := range m.pools {
+		if now.Sub(time.Unix(0, atomic.LoadInt64(&mp.lastUsed))) < m.idleTTL || mp.Stats().InUse > 0 {
+			continue
+		}
+		delete(m.pools, target)
+		idle = append(idle, mp.Pool)
+	}
+	m.mu.Unlock()
+
+	for _, p := range idle {
+		p.Close()
+	}
+}
+
+// CloseAll 关闭所有连接池, 之后 Get 返回 ErrClosed
+func (m *Manager) CloseAll() {
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return
+	}
+	m.closed = true
+	pools := m.pools
+	m.pools = make(map[string]*managedPool)
+	m.mu.Unlock()
+
+	close(m.done)
+	m.wg.Wait()
+	for _, mp := range pools {
+		mp.Close()
+	}
+}
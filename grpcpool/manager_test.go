@@ -0,0 +1,156 @@
+package grpcpool
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestManagerCreatesPoolPerTarget(t *testing.T) {
+	dial, _ := multiDial(t, "a", "b")
+	m := NewManager(
+		WithDefaultOptions(WithDial(dial), WithInitCount(1), WithMaxCount(4)),
+		WithTargetOptions("b", WithMaxCount(8)),
+	)
+	defer m.CloseAll()
+
+	if exp, act := 0, m.Len(); exp != act {
+		t.Errorf("pools created before use: %v != %v", act, exp)
+	}
+	for _, target := range []string{"a", "b", "a"} {
+		conn, err := m.Get(target)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if exp, act := target, conn.pool.addr; exp != act {
+			t.Errorf("Wrong target: %v != %v", act, exp)
+		}
+		_ = conn.Close()
+	}
+	if exp, act := 2, m.Len(); exp != act {
+		t.Errorf("Wrong pool count: %v != %v", act, exp)
+	}
+
+	a, _ := m.Pool("a")
+	b, _ := m.Pool("b")
+	if exp, act := 4, a.Stats().MaxOpen; exp != act {
+		t.Errorf("Wrong default max count: %v != %v", act, exp)
+	}
+	if exp, act := 8, b.Stats().MaxOpen; exp != act {
+		t.Errorf("Wrong target max count: %v != %v", act, exp)
+	}
+}
+
+func TestManagerClosesIdlePools(t *testing.T) {
+	dial, _ := multiDial(t, "a", "b")
+	m := NewManager(WithDefaultOptions(WithDial(dial), WithInitCount(1)), WithPoolIdleTTL(20*time.Millisecond))
+	defer m.CloseAll()
+
+	idle, err := m.Get("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = idle.Close()
+	busy, err := m.Get("b")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if exp, act := 1, m.Len(); exp != act {
+		t.Fatalf("Wrong pool count: %v != %v", act, exp)
+	}
+	if _, err := idle.pool.Get(); err != ErrClosed {
+		t.Errorf("idle pool was not closed: %v", err)
+	}
+	_ = busy.Close()
+
+	// 被关闭的连接池在下次使用时重新创建
+	conn, err := m.Get("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = conn.Close()
+}
+
+func TestManagerPoolHandleKeepsPoolAlive(t *testing.T) {
+	dial, _ := multiDial(t, "a")
+	m := NewManager(WithDefaultOptions(WithDial(dial), WithInitCount(1)), WithPoolIdleTTL(20*time.Millisecond))
+	defer m.CloseAll()
+
+	p, err := m.Pool("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 直接通过连接池获取连接, 持续使用超过 idleTTL
+	for deadline := time.Now().Add(100 * time.Millisecond); time.Now().Before(deadline); {
+		conn, err := p.Get()
+		if err != nil {
+			t.Fatalf("Get through pool handle: %v", err)
+		}
+		_ = conn.Close()
+		time.Sleep(5 * time.Millisecond)
+	}
+	if exp, act := 1, m.Len(); exp != act {
+		t.Errorf("Wrong pool count: %v != %v", act, exp)
+	}
+}
+
+func TestManagerPoolHandleSurvivesReap(t *testing.T) {
+	dial, _ := multiDial(t, "a")
+	m := NewManager(WithDefaultOptions(WithDial(dial), WithInitCount(1)), WithPoolIdleTTL(20*time.Millisecond))
+	defer m.CloseAll()
+
+	p, err := m.Pool("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(60 * time.Millisecond)
+	if exp, act := 0, m.Len(); exp != act {
+		t.Fatalf("idle pool was not closed: %v != %v", act, exp)
+	}
+
+	// 持有的连接池已经被关闭, Get 通过 Manager 重新创建
+	for _, get := range []func() (*Conn, error){p.Get, func() (*Conn, error) {
+		return p.GetContext(context.Background())
+	}} {
+		conn, err := get()
+		if err != nil {
+			t.Fatalf("Get through reaped pool handle: %v", err)
+		}
+		_ = conn.Close()
+	}
+	if exp, act := 1, m.Len(); exp != act {
+		t.Errorf("Wrong pool count: %v != %v", act, exp)
+	}
+}
+
+func TestManagerTinyIdleTTL(t *testing.T) {
+	dial, _ := multiDial(t, "a")
+	m := NewManager(WithDefaultOptions(WithDial(dial)), WithPoolIdleTTL(time.Nanosecond))
+	defer m.CloseAll()
+
+	conn, err := m.Get("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = conn.Close()
+}
+
+func TestManagerCloseAll(t *testing.T) {
+	dial, _ := multiDial(t, "a")
+	m := NewManager(WithDefaultOptions(WithDial(dial)))
+	p, err := m.Pool("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.CloseAll()
+	m.CloseAll()
+
+	if _, err := p.Get(); err != ErrClosed {
+		t.Errorf("pool was not closed: %v", err)
+	}
+	if _, err := m.Get("a"); err != ErrClosed {
+		t.Errorf("Get after CloseAll: %v want %v", err, ErrClosed)
+	}
+}
@@ -0,0 +1,204 @@
+package grpcpool
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Balancer 多地址连接池选择节点的策略
+type Balancer int
+
+const (
+	// RoundRobin 轮询
+	RoundRobin Balancer = iota
+	// LeastInFlight 选择已取出连接最少的节点
+	LeastInFlight
+	// PowerOfTwoChoices 随机选择两个节点, 取已取出连接较少的一个
+	PowerOfTwoChoices
+)
+
+type (
+	// endpoint 一个地址对应的子连接池
+	endpoint struct {
+		pool *chanPool
+		// failures 连续失败的次数
+		failures int64
+		// ejectedUntil 节点被摘除到的时间, UnixNano
+		ejectedUntil int64
+	}
+
+	multiPool struct {
+		endpoints []*endpoint
+		balancer  Balancer
+		next      uint64
+
+		maxFailures   int64
+		ejectDuration time.Duration
+		getTimeout    time.Duration
+
+		rmu  sync.Mutex
+		rand *rand.Rand
+		// created 任一节点新建连接时广播
+		created *signal
+		// done 关闭时通知 Ready 等待者
+		done      chan struct{}
+		closeOnce sync.Once
+	}
+)
+
+// NewMultiPool 为每个地址创建一个子连接池, 按 Balancer 策略在节点间选择连接
+// 连续失败 maxFailures 次的节点会被暂时摘除 ejectDuration 时间
+func NewMultiPool(addrs []string, opts ...OptFn) (Pool, error) {
+	op := defaultOptions
+	for _, fn := range opts {
+		fn(&op)
+	}
+	if len(addrs) == 0 {
+		return nil, ErrInvalidPoolSetting
+	}
+	m := &multiPool{
+		balancer:      op.balancer,
+		maxFailures:   int64(op.maxFailures),
+		ejectDuration: op.ejectDuration,
+		getTimeout:    op.getTimeout,
+		rand:          rand.New(rand.NewSource(time.Now().UnixNano())),
+		created:       newSignal(),
+		done:          make(chan struct{}),
+	}
+	for _, addr := range addrs {
+		pool, err := newChanPool(addr, op, m.created)
+		if err != nil {
+			m.Close()
+			return nil, err
+		}
+		e := &endpoint{pool: pool}
+		pool.onReturn = func(useless bool) {
+			if useless {
+				m.failure(e)
+			} else {
+				m.success(e)
+			}
+		}
+		m.endpoints = append(m.endpoints, e)
+	}
+	return m, nil
+}
+
+// Get 获取一个连接, 最多等待 getTimeout
+func (m *multiPool) Get() (*Conn, error) {
+	ctx := context.Background()
+	if m.getTimeout > 0 {
+		var cancelFunc context.CancelFunc
+		ctx, cancelFunc = context.WithTimeout(ctx, m.getTimeout)
+		defer cancelFunc()
+	}
+	return m.GetContext(ctx)
+}
+
+// GetContext 按策略选择节点获取连接, 失败时依次尝试其他可用节点
+func (m *multiPool) GetContext(ctx context.Context) (*Conn, error) {
+	candidates := m.available(time.Now())
+	var lastErr error
+	for len(candidates) > 0 {
+		i := m.pick(candidates)
+		e := candidates[i]
+		conn, err := e.pool.GetContext(ctx)
+		if err == nil {
+			return conn, nil
+		}
+		if err == ErrClosed {
+			return nil, err
+		}
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		lastErr = err
+		m.failure(e)
+		candidates = append(candidates[:i], candidates[i+1:]...)
+	}
+	return nil, lastErr
+}
+
+// available 返回未被摘除的节点, 全部被摘除时返回所有节点
+func (m *multiPool) available(now time.Time) []*endpoint {
+	candidates := make([]*endpoint, 0, len(m.endpoints))
+	for _, e := range m.endpoints {
+		if atomic.LoadInt64(&e.ejectedUntil) <= now.UnixNano() {
+			candidates = append(candidates, e)
+		}
+	}
+	if len(candidates) == 0 {
+		candidates = append(candidates, m.endpoints...)
+	}
+	return candidates
+}
+
+// pick 按策略从 candidates 中选择一个节点, 返回其下标
+func (m *multiPool) pick(candidates []*endpoint) int {
+	if len(candidates) == 1 {
+		return 0
+	}
+	switch m.balancer {
+	case LeastInFlight:
+		best := 0
+		for i := 1; i < len(candidates); i++ {
+			if candidates[i].inFlight() < candidates[best].inFlight() {
+				best = i
+			}
+		}
+		return best
+	case PowerOfTwoChoices:
+		m.rmu.Lock()
+		a := m.rand.Intn(len(candidates))
+		b := m.rand.Intn(len(candidates) - 1)
+		m.rmu.Unlock()
+		if b >= a {
+			b++
+		}
+		if candidates[b].inFlight() < candidates[a].inFlight() {
+			return b
+		}
+		return a
+	default:
+		return int((atomic.AddUint64(&m.next, 1) - 1) % uint64(len(candidates)))
+	}
+}
+
+func (m *multiPool) failure(e *endpoint) {
+	if m.maxFailures <= 0 {
+		return
+	}
+	if atomic.AddInt64(&e.failures, 1) >= m.maxFailures {
+		atomic.StoreInt64(&e.failures, 0)
+		atomic.StoreInt64(&e.ejectedUntil, time.Now().Add(m.ejectDuration).UnixNano())
+	}
+}
+
+func (m *multiPool) success(e *endpoint) {
+	atomic.StoreInt64(&e.failures, 0)
+}
+
+func (e *endpoint) inFlight() int64 {
+	return atomic.LoadInt64(&e.pool.inUse)
+}
+
+func (m *multiPool) Close() {
+	m.closeOnce.Do(func() {
+		close(m.done)
+	})
+	for _, e := range m.endpoints {
+		e.pool.Close()
+	}
+}
+
+// Len 所有节点的空闲连接数之和
+func (m *multiPool) Len() int {
+	var n int
+	for _, e := range m.endpoints {
+		n += e.pool.Len()
+	}
+	return n
+}
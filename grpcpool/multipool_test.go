@@ -0,0 +1,121 @@
+package grpcpool
+
+import (
+	"errors"
+	"math"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+func multiDial(t *testing.T, good ...string) (Factory, *int64) {
+	servers := make(map[string]*bufServer, len(good))
+	for _, addr := range good {
+		servers[addr] = newBufServer(t)
+	}
+	var failed int64
+	return func(addr string) (*grpc.ClientConn, error) {
+		s, ok := servers[addr]
+		if !ok {
+			atomic.AddInt64(&failed, 1)
+			return nil, errors.New("unreachable")
+		}
+		return s.dial(addr)
+	}, &failed
+}
+
+func getAll(t *testing.T, p Pool, n int) map[string]int {
+	t.Helper()
+	picked := make(map[string]int)
+	for i := 0; i < n; i++ {
+		conn, err := p.Get()
+		if err != nil {
+			t.Fatal(err)
+		}
+		picked[conn.pool.addr]++
+	}
+	return picked
+}
+
+func TestMultiPoolRoundRobin(t *testing.T) {
+	dial, _ := multiDial(t, "a", "b", "c")
+	p, err := NewMultiPool([]string{"a", "b", "c"}, WithDial(dial), WithInitCount(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	picked := getAll(t, p, 6)
+	for _, addr := range []string{"a", "b", "c"} {
+		if exp, act := 2, picked[addr]; exp != act {
+			t.Errorf("Wrong pick count of %s: %v != %v", addr, act, exp)
+		}
+	}
+}
+
+func TestMultiPoolRoundRobinCounterWraps(t *testing.T) {
+	dial, _ := multiDial(t, "a", "b", "c")
+	p, err := NewMultiPool([]string{"a", "b", "c"}, WithDial(dial), WithInitCount(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	// 计数器超过 int 的范围后下标仍然有效
+	m := p.(*multiPool)
+	atomic.StoreUint64(&m.next, math.MaxUint64-2)
+	for i := 0; i < 6; i++ {
+		if idx := m.pick(m.endpoints); idx < 0 || idx >= len(m.endpoints) {
+			t.Fatalf("pick %d: index %d out of range", i, idx)
+		}
+	}
+}
+
+func TestMultiPoolLeastInFlight(t *testing.T) {
+	dial, _ := multiDial(t, "a", "b")
+	p, err := NewMultiPool([]string{"a", "b"}, WithDial(dial), WithInitCount(0), WithBalancer(LeastInFlight))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	picked := getAll(t, p, 4)
+	if picked["a"] != 2 || picked["b"] != 2 {
+		t.Errorf("Wrong pick distribution: %v", picked)
+	}
+}
+
+func TestMultiPoolPowerOfTwoChoices(t *testing.T) {
+	dial, _ := multiDial(t, "a", "b")
+	p, err := NewMultiPool([]string{"a", "b"}, WithDial(dial), WithInitCount(0), WithBalancer(PowerOfTwoChoices))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	// 只有两个节点时总会比较这两个节点, 结果与 LeastInFlight 一致
+	picked := getAll(t, p, 6)
+	if picked["a"] != 3 || picked["b"] != 3 {
+		t.Errorf("Wrong pick distribution: %v", picked)
+	}
+}
+
+func TestMultiPoolEjectsFailingEndpoint(t *testing.T) {
+	dial, failed := multiDial(t, "good")
+	p, err := NewMultiPool([]string{"good", "bad"}, WithDial(dial), WithInitCount(0),
+		WithOutlierDetection(2, time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	picked := getAll(t, p, 8)
+	if exp, act := 8, picked["good"]; exp != act {
+		t.Errorf("Wrong pick count of good: %v != %v", act, exp)
+	}
+	if exp, act := int64(2), atomic.LoadInt64(failed); exp != act {
+		t.Errorf("bad endpoint was not ejected, dial attempts: %v != %v", act, exp)
+	}
+}
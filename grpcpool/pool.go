@@ -1,7 +1,9 @@
 package grpcpool
 
 import (
+	"context"
 	"errors"
+	"fmt"
 )
 
 var (
@@ -16,8 +18,38 @@ type (
 	Pool interface {
 		Get() (*Conn, error)
 
+		// GetContext 获取一个连接, 等待时间由 ctx 的超时和取消控制
+		GetContext(ctx context.Context) (*Conn, error)
+
 		Close()
 
+		// Drain 停止分配连接, 等待已取出的连接归还或 ctx 结束后关闭连接池, 返回强制关闭的连接数
+		Drain(ctx context.Context) (int, error)
+
 		Len() int
+
+		// Stats 返回连接池的统计信息
+		Stats() PoolStats
+
+		// Ready 等待状态为 READY 的连接数达到 n, ctx 结束时返回最近一次建立连接失败的错误
+		Ready(ctx context.Context, n int) error
+	}
+
+	// DialError 建立连接失败, errors.Is(err, ErrInvalidFactory) 为 true
+	DialError struct {
+		Addr string
+		Err  error
 	}
 )
+
+func (e *DialError) Error() string {
+	return fmt.Sprintf("%s: dial %s: %v", ErrInvalidFactory, e.Addr, e.Err)
+}
+
+func (e *DialError) Unwrap() error {
+	return e.Err
+}
+
+func (e *DialError) Is(target error) bool {
+	return target == ErrInvalidFactory
+}
@@ -0,0 +1,179 @@
+package grpcpool
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// getShared 共享模式下选择负载最小的连接, 所有连接都达到 maxInFlight 时新建连接或等待
+func (c *chanPool) getShared(ctx context.Context) (*Conn, error) {
+	var start time.Time
+	defer func() {
+		if !start.IsZero() {
+			c.stats.wait(time.Since(start))
+		}
+	}()
+	for {
+		if conn := c.leastLoaded(); conn != nil {
+			return c.lease(conn), nil
+		}
+		conn, ok, err := c.grow()
+		if ok {
+			if err != nil {
+				return nil, err
+			}
+			c.smu.Lock()
+			conn.inFlight = 1
+			c.shared = append(c.shared, conn)
+			c.smu.Unlock()
+			return c.lease(conn), nil
+		}
+
+		if start.IsZero() {
+			start = time.Now()
+		}
+		select {
+		case <-c.notify:
+		case <-c.done:
+			return nil, ErrClosed
+		case <-ctx.Done():
+			if ctx.Err() == context.DeadlineExceeded {
+				c.stats.timeout()
+				return nil, ErrConnectionLess
+			}
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// leastLoaded 选择分配次数最少且未达到 maxInFlight 的连接, 并占用一次
+// 空闲的不可用或过期连接会被关闭
+func (c *chanPool) leastLoaded() *Conn {
+	now := time.Now()
+	var best *Conn
+	var stale []*Conn
+	c.smu.Lock()
+	live := c.shared[:0]
+	for _, conn := range c.shared {
+		if conn.inFlight == 0 && (conn.isUseless() || !conn.healthy() || conn.expired(now, c.idleTimeout, c.maxLifetime)) {
+			stale = append(stale, conn)
+			continue
+		}
+		live = append(live, conn)
+		if conn.isUseless() || !conn.healthy() || conn.inFlight >= c.maxInFlight {
+			continue
+		}
+		if best == nil || conn.inFlight < best.inFlight {
+			best = conn
+		}
+	}
+	for i := len(live); i < len(c.shared); i++ {
+		c.shared[i] = nil
+	}
+	c.shared = live
+	if best != nil {
+		best.inFlight++
+	}
+	c.smu.Unlock()
+
+	for _, conn := range stale {
+		c.discard(conn)
+	}
+	return best
+}
+
+// lease 为调用方包装一个共享的连接, 调用方 Close 时归还占用次数
+func (c *chanPool) lease(conn *Conn) *Conn {
+	atomic.AddInt64(&c.inUse, 1)
+	conn.mu.RLock()
+	cc := conn.ClientConn
+	conn.mu.RUnlock()
+	return &Conn{ClientConn: cc, pool: c, parent: conn, checkedOut: true}
+}
+
+// unlease 归还一次占用, useless 时标记底层连接不可用, 不再被使用后关闭
+func (c *chanPool) unlease(conn *Conn, useless bool) {
+	if useless {
+		conn.MarkUseless()
+	}
+	c.smu.Lock()
+	conn.inFlight--
+	// 连接池关闭后, 连接在最后一个调用方归还时关闭
+	remove := conn.inFlight == 0 && (conn.isUseless() || c.isClosed()) && c.removeShared(conn)
+	c.smu.Unlock()
+	if remove {
+		c.retire(conn)
+	}
+
+	c.wake()
+}
+
+// wake 唤醒一个等待共享连接的调用方
+func (c *chanPool) wake() {
+	select {
+	case c.notify <- struct{}{}:
+	default:
+	}
+}
+
+// removeShared 从 shared 中移除连接, 调用方需持有 smu
+func (c *chanPool) removeShared(conn *Conn) bool {
+	for i, sc := range c.shared {
+		if sc == conn {
+			last := len(c.shared) - 1
+			c.shared[i] = c.shared[last]
+			c.shared[last] = nil
+			c.shared = c.shared[:last]
+			return true
+		}
+	}
+	return false
+}
+
+// eachIdleShared 取出当前没有被使用的共享连接交给 fn 处理, fn 返回 true 时放回连接池
+func (c *chanPool) eachIdleShared(fn func(conn *Conn) bool) {
+	var idle []*Conn
+	c.smu.Lock()
+	for i := 0; i < len(c.shared); {
+		if conn := c.shared[i]; conn.inFlight == 0 {
+			idle = append(idle, conn)
+			c.removeShared(conn)
+			continue
+		}
+		i++
+	}
+	c.smu.Unlock()
+
+	for _, conn := range idle {
+		if fn(conn) {
+			c.smu.Lock()
+			c.shared = append(c.shared, conn)
+			c.smu.Unlock()
+		}
+		c.wake()
+	}
+}
+
+func (c *chanPool) idleShared() int {
+	c.smu.Lock()
+	defer c.smu.Unlock()
+	var n int
+	for _, conn := range c.shared {
+		if conn.inFlight == 0 {
+			n++
+		}
+	}
+	return n
+}
+
+// closeShared 关闭所有共享连接, 调用方仍持有的连接随之失效
+func (c *chanPool) closeShared() {
+	c.smu.Lock()
+	shared := c.shared
+	c.shared = nil
+	c.smu.Unlock()
+	for _, conn := range shared {
+		c.retire(conn)
+	}
+}
@@ -0,0 +1,121 @@
+package grpcpool
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSharedPoolRoutesToLeastLoaded(t *testing.T) {
+	s := newBufServer(t)
+	p, err := NewPool("bufnet", WithDial(s.dial), WithInitCount(0), WithMaxCount(2), WithMaxInFlight(2),
+		WithGetTimeout(20*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	leases := make([]*Conn, 0, 4)
+	picked := make(map[*Conn]int)
+	for i := 0; i < 4; i++ {
+		conn, err := p.Get()
+		if err != nil {
+			t.Fatal(err)
+		}
+		leases = append(leases, conn)
+		picked[conn.parent]++
+	}
+	if len(picked) != 2 {
+		t.Fatalf("Wrong connection count: %v != %v", len(picked), 2)
+	}
+	for parent, n := range picked {
+		if n != 2 {
+			t.Errorf("Wrong lease count of %p: %v != %v", parent, n, 2)
+		}
+	}
+	if exp, act := int64(2), s.dialCount(); exp != act {
+		t.Errorf("Wrong dial count: %v != %v", act, exp)
+	}
+
+	if _, err := p.Get(); err != ErrConnectionLess {
+		t.Fatalf("Get on saturated pool: %v want %v", err, ErrConnectionLess)
+	}
+
+	_ = leases[0].Close()
+	conn, err := p.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conn.parent != leases[0].parent {
+		t.Errorf("Get did not route to the least loaded connection")
+	}
+	stats := p.Stats()
+	if stats.Total != 2 || stats.Idle != 0 || stats.InUse != 2 {
+		t.Errorf("Wrong connection counts: %+v", stats)
+	}
+}
+
+func TestSharedPoolDropsUselessConnection(t *testing.T) {
+	s := newBufServer(t)
+	p, err := NewPool("bufnet", WithDial(s.dial), WithInitCount(1), WithMaxCount(1), WithMaxInFlight(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	a, err := p.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := p.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.parent != b.parent {
+		t.Fatalf("leases do not share the connection")
+	}
+
+	a.MarkUseless()
+	_ = a.Close()
+	_ = a.Close()
+	if exp, act := 1, p.Stats().Total; exp != act {
+		t.Errorf("connection closed while still in use: %v != %v", act, exp)
+	}
+	_ = b.Close()
+	if exp, act := 0, p.Stats().Total; exp != act {
+		t.Errorf("useless connection was not closed: %v != %v", act, exp)
+	}
+
+	c, err := p.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.parent == a.parent {
+		t.Errorf("Get returned a useless connection")
+	}
+}
+
+func TestSharedPoolSkipsUnhealthyConnection(t *testing.T) {
+	s := newBufServer(t)
+	p, err := NewPool("bufnet", WithDial(s.dial), WithInitCount(1), WithMaxCount(2), WithMaxInFlight(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	a, err := p.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+	// 仍有租约的连接不会被关闭, 但也不应再分配出去
+	_ = a.parent.ClientConn.Close()
+
+	b, err := p.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+	if b.parent == a.parent {
+		t.Errorf("Get returned an unhealthy connection")
+	}
+}
@@ -0,0 +1,191 @@
+package grpcpool
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type (
+	// PoolStats 连接池的统计信息
+	PoolStats struct {
+		MaxOpen int // 最大连接数
+
+		Total int // 存活的连接数, 包括空闲的和已被取出的
+		Idle  int // 空闲的连接数
+		InUse int // 已被取出的连接数
+
+		WaitCount    int64         // 等待连接的总次数
+		WaitDuration time.Duration // 等待连接的总时长
+		DialFailures int64         // 建立连接失败的总次数
+		Evictions    int64         // 因不可用或过期被关闭的连接总数
+		Timeouts     int64         // 等待连接超时的总次数
+	}
+
+	// counters 连接池的累计计数
+	counters struct {
+		waitCount    int64
+		waitDuration int64
+		dialFailures int64
+		evictions    int64
+		timeouts     int64
+	}
+)
+
+func (s *counters) wait(d time.Duration) {
+	atomic.AddInt64(&s.waitCount, 1)
+	atomic.AddInt64(&s.waitDuration, int64(d))
+}
+
+func (s *counters) dialFailure() {
+	atomic.AddInt64(&s.dialFailures, 1)
+}
+
+func (s *counters) evict() {
+	atomic.AddInt64(&s.evictions, 1)
+}
+
+func (s *counters) timeout() {
+	atomic.AddInt64(&s.timeouts, 1)
+}
+
+func (c *chanPool) Stats() PoolStats {
+	total, idle := int(atomic.LoadInt64(&c.active)), c.Len()
+	inUse := int(atomic.LoadInt64(&c.inUse))
+	if c.maxInFlight > 0 {
+		// 共享模式下 inUse 为调用方持有的次数, 换算为正在使用的连接数
+		inUse = total - idle
+	}
+	return PoolStats{
+		MaxOpen:      int(c.maxCount),
+		Total:        total,
+		Idle:         idle,
+		InUse:        inUse,
+		WaitCount:    atomic.LoadInt64(&c.stats.waitCount),
+		WaitDuration: time.Duration(atomic.LoadInt64(&c.stats.waitDuration)),
+		DialFailures: atomic.LoadInt64(&c.stats.dialFailures),
+		Evictions:    atomic.LoadInt64(&c.stats.evictions),
+		Timeouts:     atomic.LoadInt64(&c.stats.timeouts),
+	}
+}
+
+// Stats 所有节点统计信息之和
+func (m *multiPool) Stats() PoolStats {
+	var stats PoolStats
+	for _, e := range m.endpoints {
+		s := e.pool.Stats()
+		stats.MaxOpen += s.MaxOpen
+		stats.Total += s.Total
+		stats.Idle += s.Idle
+		stats.InUse += s.InUse
+		stats.WaitCount += s.WaitCount
+		stats.WaitDuration += s.WaitDuration
+		stats.DialFailures += s.DialFailures
+		stats.Evictions += s.Evictions
+		stats.Timeouts += s.Timeouts
+	}
+	return stats
+}
+
+// StatsCollector 以 Prometheus 文本格式导出连接池的统计信息
+type StatsCollector struct {
+	namespace string
+	mu        sync.RWMutex
+	pools     map[string]Pool
+}
+
+// NewStatsCollector 创建一个统计信息导出器, 指标名以 namespace 为前缀
+func NewStatsCollector(namespace string) *StatsCollector {
+	if namespace == "" {
+		namespace = "grpcpool"
+	}
+	return &StatsCollector{
+		namespace: namespace,
+		pools:     make(map[string]Pool),
+	}
+}
+
+// Register 注册一个连接池, name 作为指标的 pool 标签
+func (sc *StatsCollector) Register(name string, p Pool) {
+	sc.mu.Lock()
+	sc.pools[name] = p
+	sc.mu.Unlock()
+}
+
+// Unregister 取消注册一个连接池
+func (sc *StatsCollector) Unregister(name string) {
+	sc.mu.Lock()
+	delete(sc.pools, name)
+	sc.mu.Unlock()
+}
+
+type metric struct {
+	name, help, typ string
+	value           func(s PoolStats) float64
+}
+
+var metrics = []metric{
+	{"max_open_connections", "Maximum number of connections.", "gauge", func(s PoolStats) float64 { return float64(s.MaxOpen) }},
+	{"open_connections", "Number of established connections, both idle and in use.", "gauge", func(s PoolStats) float64 { return float64(s.Total) }},
+	{"idle_connections", "Number of idle connections.", "gauge", func(s PoolStats) float64 { return float64(s.Idle) }},
+	{"in_use_connections", "Number of connections currently in use.", "gauge", func(s PoolStats) float64 { return float64(s.InUse) }},
+	{"wait_count_total", "Total number of connections waited for.", "counter", func(s PoolStats) float64 { return float64(s.WaitCount) }},
+	{"wait_duration_seconds_total", "Total time blocked waiting for a connection.", "counter", func(s PoolStats) float64 { return s.WaitDuration.Seconds() }},
+	{"dial_failures_total", "Total number of failed dials.", "counter", func(s PoolStats) float64 { return float64(s.DialFailures) }},
+	{"evictions_total", "Total number of connections closed as unhealthy or expired.", "counter", func(s PoolStats) float64 { return float64(s.Evictions) }},
+	{"timeouts_total", "Total number of Get calls that timed out.", "counter", func(s PoolStats) float64 { return float64(s.Timeouts) }},
+}
+
+// WriteTo 将所有已注册连接池的统计信息写入 w
+func (sc *StatsCollector) WriteTo(w io.Writer) (int64, error) {
+	sc.mu.RLock()
+	names := make([]string, 0, len(sc.pools))
+	stats := make(map[string]PoolStats, len(sc.pools))
+	for name, p := range sc.pools {
+		names = append(names, name)
+		stats[name] = p.Stats()
+	}
+	sc.mu.RUnlock()
+	sort.Strings(names)
+
+	cw := &countWriter{w: bufio.NewWriter(w)}
+	for _, m := range metrics {
+		name := sc.namespace + "_" + m.name
+		fmt.Fprintf(cw, "# HELP %s %s\n", name, m.help)
+		fmt.Fprintf(cw, "# TYPE %s %s\n", name, m.typ)
+		for _, pool := range names {
+			fmt.Fprintf(cw, "%s{pool=%q} %g\n", name, pool, m.value(stats[pool]))
+		}
+	}
+	if err := cw.w.Flush(); err != nil && cw.err == nil {
+		cw.err = err
+	}
+	return cw.n, cw.err
+}
+
+// ServeHTTP 实现 http.Handler, 可直接挂载为 /metrics 接口
+func (sc *StatsCollector) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_, _ = sc.WriteTo(w)
+}
+
+type countWriter struct {
+	w   *bufio.Writer
+	n   int64
+	err error
+}
+
+func (cw *countWriter) Write(p []byte) (int, error) {
+	if cw.err != nil {
+		return 0, cw.err
+	}
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	cw.err = err
+	return n, err
+}
@@ -0,0 +1,67 @@
+package grpcpool
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPoolStats(t *testing.T) {
+	s := newBufServer(t)
+	p, err := NewPool("bufnet", WithDial(s.dial), WithInitCount(1), WithMaxCount(1),
+		WithGetTimeout(20*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	if _, err := p.Get(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Get(); err != ErrConnectionLess {
+		t.Fatalf("Get on exhausted pool: %v want %v", err, ErrConnectionLess)
+	}
+
+	stats := p.Stats()
+	if stats.MaxOpen != 1 || stats.Total != 1 || stats.Idle != 0 || stats.InUse != 1 {
+		t.Errorf("Wrong connection counts: %+v", stats)
+	}
+	if stats.WaitCount != 1 || stats.Timeouts != 1 {
+		t.Errorf("Wrong wait counts: %+v", stats)
+	}
+	if stats.WaitDuration < 20*time.Millisecond {
+		t.Errorf("Wrong wait duration: %v", stats.WaitDuration)
+	}
+}
+
+func TestStatsCollector(t *testing.T) {
+	s := newBufServer(t)
+	p, err := NewPool("bufnet", WithDial(s.dial), WithInitCount(2), WithMaxCount(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	sc := NewStatsCollector("")
+	sc.Register("user", p)
+	var buf bytes.Buffer
+	n, err := sc.WriteTo(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp, act := int64(buf.Len()), n; exp != act {
+		t.Errorf("Wrong written count: %v != %v", act, exp)
+	}
+	for _, line := range []string{
+		"# TYPE grpcpool_max_open_connections gauge",
+		`grpcpool_max_open_connections{pool="user"} 3`,
+		`grpcpool_idle_connections{pool="user"} 2`,
+		"# TYPE grpcpool_timeouts_total counter",
+		`grpcpool_timeouts_total{pool="user"} 0`,
+	} {
+		if !strings.Contains(buf.String(), line+"\n") {
+			t.Errorf("missing line %q in:\n%s", line, buf.String())
+		}
+	}
+}
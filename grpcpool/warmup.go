@@ -0,0 +1,174 @@
+package grpcpool
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+)
+
+// warmUpBackoff 后台建立连接失败后的初始重试间隔, 每次失败翻倍, 最大为 backoffMaxDelay
+const warmUpBackoff = 100 * time.Millisecond
+
+// signal 广播通知, broadcast 会唤醒所有在 wait 返回的 channel 上等待的调用方
+type signal struct {
+	mu sync.Mutex
+	ch chan struct{}
+}
+
+func newSignal() *signal {
+	return &signal{ch: make(chan struct{})}
+}
+
+func (s *signal) wait() <-chan struct{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.ch
+}
+
+func (s *signal) broadcast() {
+	s.mu.Lock()
+	close(s.ch)
+	s.ch = make(chan struct{})
+	s.mu.Unlock()
+}
+
+// dialFailed 记录建立连接失败的错误, 返回包装后的 *DialError
+func (c *chanPool) dialFailed(err error) error {
+	c.stats.dialFailure()
+	dialErr := &DialError{Addr: c.addr, Err: err}
+	c.dialErrMu.Lock()
+	c.lastDialErr = dialErr
+	c.dialErrMu.Unlock()
+	return dialErr
+}
+
+func (c *chanPool) lastDialError() error {
+	c.dialErrMu.Lock()
+	defer c.dialErrMu.Unlock()
+	return c.lastDialErr
+}
+
+// addIdle 将新建的连接放入连接池
+func (c *chanPool) addIdle(conn *Conn) {
+	if c.maxInFlight > 0 {
+		c.smu.Lock()
+		c.shared = append(c.shared, conn)
+		c.smu.Unlock()
+		c.wake()
+		return
+	}
+	_ = c.put(conn)
+}
+
+// warmUp 在后台建立连接直到存活连接数达到 initCount, 失败时按指数退避重试
+func (c *chanPool) warmUp() {
+	defer c.wg.Done()
+	delay := warmUpBackoff
+	for atomic.LoadInt64(&c.active) < c.initCount {
+		select {
+		case <-c.done:
+			return
+		default:
+		}
+		conn, ok, err := c.grow()
+		if !ok {
+			return
+		}
+		if err == nil {
+			c.addIdle(conn)
+			delay = warmUpBackoff
+			continue
+		}
+
+		t := time.NewTimer(delay)
+		select {
+		case <-c.done:
+			t.Stop()
+			return
+		case <-t.C:
+		}
+		if delay *= 2; delay > backoffMaxDelay {
+			delay = backoffMaxDelay
+		}
+	}
+}
+
+// Ready 等待状态为 READY 的连接数达到 n, 拨号不阻塞, 只建立了 ClientConn 的连接不计入
+// ctx 结束时如果有建立连接失败的错误, 返回该 *DialError, 否则返回 ctx.Err()
+func (c *chanPool) Ready(ctx context.Context, n int) error {
+	return waitReady(ctx, c.created, c.done, func(watch context.Context) bool {
+		return c.connected(watch) >= int64(n)
+	}, c.lastDialError)
+}
+
+// Ready 等待所有节点状态为 READY 的连接数之和达到 n
+func (m *multiPool) Ready(ctx context.Context, n int) error {
+	return waitReady(ctx, m.created, m.done, func(watch context.Context) bool {
+		var connected int64
+		for _, e := range m.endpoints {
+			connected += e.pool.connected(watch)
+		}
+		return connected >= int64(n)
+	}, func() error {
+		var err error
+		for _, e := range m.endpoints {
+			if dialErr := e.pool.lastDialError(); dialErr != nil {
+				err = dialErr
+			}
+		}
+		return err
+	})
+}
+
+// connected 返回状态为 READY 的连接数, 其他连接的状态变化时广播 created, 直到 watch 结束
+func (c *chanPool) connected(watch context.Context) int64 {
+	var n int64
+	c.owners.Range(func(key, _ interface{}) bool {
+		cc := key.(*grpc.ClientConn)
+		state := cc.GetState()
+		if state == connectivity.Ready {
+			n++
+			return true
+		}
+		if state == connectivity.Idle {
+			cc.Connect()
+		}
+		go func() {
+			if cc.WaitForStateChange(watch, state) {
+				c.created.broadcast()
+			}
+		}()
+		return true
+	})
+	return n
+}
+
+// waitReady 每次检查时创建新的 watch, 上一轮检查启动的状态监听随之结束
+func waitReady(ctx context.Context, created *signal, done <-chan struct{}, ready func(watch context.Context) bool, lastErr func() error) error {
+	for {
+		// 先取得通知 channel 再检查, 避免错过检查之后的广播
+		wait := created.wait()
+		watch, cancel := context.WithCancel(ctx)
+		if ready(watch) {
+			cancel()
+			return nil
+		}
+		select {
+		case <-wait:
+			cancel()
+		case <-done:
+			cancel()
+			return ErrClosed
+		case <-ctx.Done():
+			cancel()
+			if err := lastErr(); err != nil {
+				return err
+			}
+			return ctx.Err()
+		}
+	}
+}
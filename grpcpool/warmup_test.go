@@ -0,0 +1,134 @@
+package grpcpool
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+var errRefused = errors.New("connection refused")
+
+// flakyDial 前 failures 次建立连接失败, failures < 0 时一直失败
+func flakyDial(s *bufServer, failures int64) Factory {
+	var calls int64
+	return func(addr string) (*grpc.ClientConn, error) {
+		if n := atomic.AddInt64(&calls, 1); failures < 0 || n <= failures {
+			return nil, errRefused
+		}
+		return s.dial(addr)
+	}
+}
+
+func TestNewPoolReturnsDialError(t *testing.T) {
+	s := newBufServer(t)
+	_, err := NewPool("bufnet", WithDial(flakyDial(s, -1)))
+	if !errors.Is(err, ErrInvalidFactory) {
+		t.Errorf("errors.Is(%v, ErrInvalidFactory) = false", err)
+	}
+	if !errors.Is(err, errRefused) {
+		t.Errorf("underlying dial error was lost: %v", err)
+	}
+	var dialErr *DialError
+	if !errors.As(err, &dialErr) || dialErr.Addr != "bufnet" {
+		t.Errorf("Wrong dial error: %#v", err)
+	}
+}
+
+func TestWarmUpToleratesDialFailures(t *testing.T) {
+	s := newBufServer(t)
+	p, err := NewPool("bufnet", WithDial(flakyDial(s, 3)), WithInitCount(2), WithWarmUp())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := p.Ready(ctx, 2); err != nil {
+		t.Fatal(err)
+	}
+	stats := p.Stats()
+	if exp, act := 2, stats.Total; exp != act {
+		t.Errorf("Wrong total count: %v != %v", act, exp)
+	}
+	if exp, act := int64(3), stats.DialFailures; exp != act {
+		t.Errorf("Wrong dial failures: %v != %v", act, exp)
+	}
+}
+
+func TestReadyReturnsLastDialError(t *testing.T) {
+	s := newBufServer(t)
+	p, err := NewMultiPool([]string{"a", "b"}, WithDial(flakyDial(s, -1)), WithInitCount(1), WithWarmUp())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err = p.Ready(ctx, 1)
+	if !errors.Is(err, errRefused) {
+		t.Errorf("Ready: %v want %v", err, errRefused)
+	}
+}
+
+func TestMultiPoolReadyReturnsAfterClose(t *testing.T) {
+	s := newBufServer(t)
+	p, err := NewMultiPool([]string{"a", "b"}, WithDial(flakyDial(s, -1)), WithInitCount(1), WithWarmUp())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	got := make(chan error, 1)
+	go func() {
+		got <- p.Ready(ctx, 1)
+	}()
+	time.Sleep(20 * time.Millisecond)
+	p.Close()
+	p.Close()
+
+	select {
+	case err := <-got:
+		if err != ErrClosed {
+			t.Errorf("Ready after Close: %v want %v", err, ErrClosed)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Ready did not return after Close")
+	}
+}
+
+func TestReadyWaitsForConnectedConns(t *testing.T) {
+	// 拨号不阻塞, 不可达的地址也能建立 ClientConn, 但不会进入 READY 状态
+	p, err := NewPool("127.0.0.1:1", WithWarmUp(), WithInitCount(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	if err := p.Ready(ctx, 3); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Ready: %v want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestReadyCountsConnectedConns(t *testing.T) {
+	s := newBufServer(t)
+	p, err := NewMultiPool([]string{"a", "b"}, WithDial(s.dial), WithInitCount(2), WithWarmUp())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := p.Ready(ctx, 4); err != nil {
+		t.Fatal(err)
+	}
+}
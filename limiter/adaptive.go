@@ -0,0 +1,208 @@
+package limiter
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+const (
+	defaultBackoffRatio = 0.9
+	defaultTolerance    = 1.5
+	defaultSmoothing    = 0.2
+	// longRTTWindow 长期延迟按最近约 longRTTWindow 个样本做指数平均
+	longRTTWindow = 600
+)
+
+type (
+	// LimitAlgorithm 根据请求的延迟和结果计算新的并发上限, 由 AdaptiveLimiter 串行调用
+	LimitAlgorithm interface {
+		// Limit 当前的并发上限
+		Limit() int
+		// Update 一个请求完成后调用, rtt 请求耗时, inFlight 请求开始时的并发数, dropped 请求失败或超时
+		// 返回新的并发上限
+		Update(rtt time.Duration, inFlight int, dropped bool) int
+	}
+
+	// AIMD 加性增乘性减, 请求被丢弃或超时时上限乘以 backoffRatio, 否则在并发接近上限时加一
+	AIMD struct {
+		limit, min, max int
+		backoffRatio    float64
+		rttTimeout      time.Duration
+	}
+
+	// Gradient 延迟梯度算法, 比较短期延迟和长期延迟, 延迟上升时按比例降低上限, 延迟平稳时逐步增加
+	Gradient struct {
+		limit     float64
+		min, max  int
+		tolerance float64
+		smoothing float64
+		// longRTT 长期延迟的指数平均, 单位纳秒
+		longRTT float64
+	}
+
+	// AdaptiveLimiter 自适应并发限制, 每个请求完成后由 LimitAlgorithm 调整 ConcurrencyLimiter 的上限
+	AdaptiveLimiter struct {
+		cl        *ConcurrencyLimiter
+		mu        sync.Mutex
+		algorithm LimitAlgorithm
+		clock     Clock
+	}
+
+	// Permit AdaptiveLimiter 发放的许可, 请求完成后必须调用 Release
+	Permit struct {
+		al       *AdaptiveLimiter
+		start    time.Time
+		inFlight int
+		once     sync.Once
+	}
+)
+
+// NewAIMD 实例化 AIMD 算法, 上限在 [min, max] 之间调整, min 至少为 1
+func NewAIMD(initial, min, max int, opts ...AIMDOptFn) *AIMD {
+	min, max = limitBounds(min, max)
+	op := aimdOptions{backoffRatio: defaultBackoffRatio}
+	for _, fn := range opts {
+		fn(&op)
+	}
+	return &AIMD{
+		limit:        clampLimit(initial, min, max),
+		min:          min,
+		max:          max,
+		backoffRatio: op.backoffRatio,
+		rttTimeout:   op.rttTimeout,
+	}
+}
+
+func (a *AIMD) Limit() int {
+	return a.limit
+}
+
+func (a *AIMD) Update(rtt time.Duration, inFlight int, dropped bool) int {
+	if dropped || a.rttTimeout > 0 && rtt > a.rttTimeout {
+		a.limit = clampLimit(int(float64(a.limit)*a.backoffRatio), a.min, a.max)
+	} else if inFlight*2 >= a.limit {
+		// 并发数不到上限的一半时, 上限不是瓶颈, 不增加
+		a.limit = clampLimit(a.limit+1, a.min, a.max)
+	}
+	return a.limit
+}
+
+// NewGradient 实例化延迟梯度算法, 上限在 [min, max] 之间调整, min 至少为 1
+func NewGradient(initial, min, max int, opts ...GradientOptFn) *Gradient {
+	min, max = limitBounds(min, max)
+	op := gradientOptions{tolerance: defaultTolerance, smoothing: defaultSmoothing}
+	for _, fn := range opts {
+		fn(&op)
+	}
+	return &Gradient{
+		limit:     float64(clampLimit(initial, min, max)),
+		min:       min,
+		max:       max,
+		tolerance: op.tolerance,
+		smoothing: op.smoothing,
+	}
+}
+
+func (g *Gradient) Limit() int {
+	return int(g.limit)
+}
+
+// Update dropped 不影响梯度算法, 请求失败通常会体现在延迟上
+func (g *Gradient) Update(rtt time.Duration, inFlight int, _ bool) int {
+	shortRTT := float64(rtt)
+	if shortRTT <= 0 {
+		return g.Limit()
+	}
+	if g.longRTT == 0 {
+		g.longRTT = shortRTT
+	} else {
+		g.longRTT += (shortRTT - g.longRTT) / longRTTWindow
+	}
+	// 长期延迟远高于短期延迟时, 说明负载已经下降, 加快长期延迟的恢复
+	if g.longRTT/shortRTT > 2 {
+		g.longRTT *= 0.95
+	}
+	if float64(inFlight) < g.limit/2 {
+		return g.Limit()
+	}
+
+	// 短期延迟在 tolerance 倍长期延迟内不降低上限, 最多一次降低一半
+	gradient := math.Max(0.5, math.Min(1, g.tolerance*g.longRTT/shortRTT))
+	// 额外允许约 sqrt(limit) 个请求排队, 用来探测更高的上限
+	next := g.limit*gradient + math.Sqrt(g.limit)
+	next = g.limit*(1-g.smoothing) + next*g.smoothing
+	g.limit = math.Max(float64(g.min), math.Min(float64(g.max), next))
+	return g.Limit()
+}
+
+// limitBounds 上限为 0 时不再有请求完成, 也就不会再调整, 所以 min 至少为 1
+func limitBounds(min, max int) (int, int) {
+	if min < 1 {
+		min = 1
+	}
+	if max < min {
+		max = min
+	}
+	return min, max
+}
+
+func clampLimit(limit, min, max int) int {
+	if limit < min {
+		return min
+	}
+	if limit > max {
+		return max
+	}
+	return limit
+}
+
+// NewAdaptiveLimiter 实例化自适应并发限制器, 初始上限为 algorithm.Limit()
+// opts 中的 WithMaxQueue 和 WithQueueTimeout 用于配置排队
+func NewAdaptiveLimiter(algorithm LimitAlgorithm, opts ...ConcurrencyOption) *AdaptiveLimiter {
+	op := applyConcurrencyOptions(opts)
+	return &AdaptiveLimiter{
+		cl:        NewConcurrencyLimiter(algorithm.Limit(), opts...),
+		algorithm: algorithm,
+		clock:     op.clock,
+	}
+}
+
+// Acquire 获取一个许可, 错误与 ConcurrencyLimiter.Acquire 相同
+func (al *AdaptiveLimiter) Acquire(ctx context.Context) (*Permit, error) {
+	if err := al.cl.Acquire(ctx); err != nil {
+		return nil, err
+	}
+	return &Permit{
+		al:       al,
+		start:    al.clock.Now(),
+		inFlight: al.cl.InFlight(),
+	}, nil
+}
+
+// Release 归还许可并根据请求耗时调整上限, dropped 表示请求失败或超时, 重复调用无效
+func (p *Permit) Release(dropped bool) {
+	p.once.Do(func() {
+		al := p.al
+		rtt := al.clock.Now().Sub(p.start)
+		al.mu.Lock()
+		limit := al.algorithm.Update(rtt, p.inFlight, dropped)
+		al.cl.SetLimit(limit)
+		al.mu.Unlock()
+		al.cl.Release()
+	})
+}
+
+// Limit 当前的并发上限
+func (al *AdaptiveLimiter) Limit() int {
+	return al.cl.Limit()
+}
+
+func (al *AdaptiveLimiter) InFlight() int {
+	return al.cl.InFlight()
+}
+
+func (al *AdaptiveLimiter) Queued() int {
+	return al.cl.Queued()
+}
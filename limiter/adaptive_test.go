@@ -0,0 +1,116 @@
+package limiter
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestAIMD(t *testing.T) {
+	a := NewAIMD(10, 5, 12, WithRTTTimeout(time.Second))
+	for i, step := range []struct {
+		rtt      time.Duration
+		inFlight int
+		dropped  bool
+		want     int
+	}{
+		{d, 2, false, 10}, // 并发数不到上限的一半, 不增加
+		{d, 5, false, 11},
+		{d, 11, false, 12},
+		{d, 12, false, 12}, // 不超过 max
+		{d, 12, true, 10},  // 12 * 0.9
+		{2 * time.Second, 10, false, 9},
+		{d, 9, true, 8},
+		{d, 8, true, 7},
+		{d, 7, true, 6},
+		{d, 6, true, 5},
+		{d, 5, true, 5}, // 不低于 min
+	} {
+		if got := a.Update(step.rtt, step.inFlight, step.dropped); got != step.want {
+			t.Errorf("step %d: Update(%v, %d, %v) = %d want %d", i, step.rtt, step.inFlight, step.dropped, got, step.want)
+		}
+	}
+}
+
+func TestAdaptiveMinLimit(t *testing.T) {
+	// min 为 0 时上限一旦降到 0 就再也不会恢复
+	a := NewAIMD(1, 0, 10)
+	if got := a.Update(d, 1, true); got != 1 {
+		t.Errorf("AIMD limit after drop = %d want 1", got)
+	}
+	g := NewGradient(1, 0, 10)
+	for i := 0; i < 20; i++ {
+		g.Update(time.Duration(i+1)*time.Second, g.Limit(), false)
+	}
+	if got := g.Limit(); got < 1 {
+		t.Errorf("Gradient limit with rising latency = %d want >= 1", got)
+	}
+}
+
+func TestGradient(t *testing.T) {
+	g := NewGradient(20, 1, 100)
+	// 延迟平稳时逐步增加
+	for i := 0; i < 20; i++ {
+		g.Update(10*time.Millisecond, g.Limit(), false)
+	}
+	grown := g.Limit()
+	if grown <= 20 {
+		t.Fatalf("limit with steady latency = %d want > 20", grown)
+	}
+	// 并发数不到上限的一半时不调整
+	if got := g.Update(10*time.Millisecond, 1, false); got != grown {
+		t.Errorf("limit when app limited = %d want %d", got, grown)
+	}
+	// 延迟大幅上升时降低
+	for i := 0; i < 20; i++ {
+		g.Update(100*time.Millisecond, g.Limit(), false)
+	}
+	if got := g.Limit(); got >= grown {
+		t.Errorf("limit with rising latency = %d want < %d", got, grown)
+	}
+}
+
+func TestAdaptiveLimiter(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1000, 0))
+	al := NewAdaptiveLimiter(NewAIMD(2, 1, 10), WithMaxQueue(1), WithClock(clock))
+	ctx := context.Background()
+
+	p1, err := al.Acquire(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p2, err := al.Acquire(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	queued := make(chan *Permit, 1)
+	go func() {
+		p, err := al.Acquire(ctx)
+		if err != nil {
+			t.Errorf("queued Acquire: %v", err)
+		}
+		queued <- p
+	}()
+	waitQueued(t, al.cl, 1)
+
+	// 成功的请求使上限增加, 排队的调用方立即得到许可
+	clock.Advance(d)
+	p2.Release(false)
+	p3 := <-queued
+	if got := al.Limit(); got != 3 {
+		t.Errorf("Limit() after success = %d want 3", got)
+	}
+	if got := al.InFlight(); got != 2 {
+		t.Errorf("InFlight() = %d want 2", got)
+	}
+
+	p1.Release(true)
+	p1.Release(true) // 重复调用无效
+	if got := al.Limit(); got != 2 {
+		t.Errorf("Limit() after drop = %d want 2", got)
+	}
+	p3.Release(false)
+	if got := al.InFlight(); got != 0 {
+		t.Errorf("InFlight() = %d want 0", got)
+	}
+}
@@ -0,0 +1,255 @@
+package limiter
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+const (
+	defaultFailureRatio     = 0.5
+	defaultMinRequests      = 20
+	defaultBreakerWindow    = 10 * time.Second
+	defaultBreakerBuckets   = 10
+	defaultOpenTimeout      = 5 * time.Second
+	defaultHalfOpenRequests = 1
+)
+
+var ErrBreakerOpen = errors.New("limiter: circuit breaker is open")
+
+// State 熔断器的状态
+type State int
+
+const (
+	// StateClosed 正常放行请求, 统计失败情况
+	StateClosed State = iota
+	// StateOpen 拒绝所有请求, openTimeout 后进入 StateHalfOpen
+	StateOpen
+	// StateHalfOpen 放行少量请求探测下游, 全部成功后恢复 StateClosed, 任一失败重新 StateOpen
+	StateHalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+type (
+	// Breaker 熔断器, 在滚动窗口内失败比例过高或连续失败过多时熔断
+	Breaker struct {
+		mu    sync.Mutex
+		state State
+		// generation 每次状态变化时递增, 忽略上一个状态中发出的请求的结果
+		generation uint64
+		openedAt   time.Time
+
+		buckets        []breakerBucket
+		bucketDuration time.Duration
+		consecutive    int
+
+		halfOpenInFlight  int
+		halfOpenSuccesses int
+
+		failureRatio     float64
+		minRequests      int
+		maxConsecutive   int
+		openTimeout      time.Duration
+		halfOpenRequests int
+		isFailure        func(err error) bool
+		onStateChange    func(from, to State)
+		clock            Clock
+	}
+
+	// breakerBucket 滚动窗口中的一个时间桶, index 为桶对应的时间序号
+	breakerBucket struct {
+		index               int64
+		successes, failures int
+	}
+)
+
+// NewBreaker 实例化一个熔断器
+// 默认 10 秒内至少 20 个请求且失败比例达到 0.5 时熔断, 5 秒后放行 1 个请求探测
+func NewBreaker(opts ...BreakerOption) *Breaker {
+	op := breakerOptions{
+		failureRatio:     defaultFailureRatio,
+		minRequests:      defaultMinRequests,
+		breakerWindow:    defaultBreakerWindow,
+		breakerBuckets:   defaultBreakerBuckets,
+		openTimeout:      defaultOpenTimeout,
+		halfOpenRequests: defaultHalfOpenRequests,
+	}
+	for _, opt := range opts {
+		opt.applyBreaker(&op)
+	}
+	op.setDefaults()
+	if op.breakerBuckets <= 0 {
+		op.breakerBuckets = defaultBreakerBuckets
+	}
+	if op.breakerWindow < time.Duration(op.breakerBuckets) {
+		op.breakerWindow = defaultBreakerWindow
+	}
+	if op.halfOpenRequests <= 0 {
+		op.halfOpenRequests = defaultHalfOpenRequests
+	}
+	if op.isFailure == nil {
+		op.isFailure = func(err error) bool {
+			return err != nil
+		}
+	}
+	return &Breaker{
+		buckets:          make([]breakerBucket, op.breakerBuckets),
+		bucketDuration:   op.breakerWindow / time.Duration(op.breakerBuckets),
+		failureRatio:     op.failureRatio,
+		minRequests:      op.minRequests,
+		maxConsecutive:   op.maxConsecutive,
+		openTimeout:      op.openTimeout,
+		halfOpenRequests: op.halfOpenRequests,
+		isFailure:        op.isFailure,
+		onStateChange:    op.onStateChange,
+		clock:            op.clock,
+	}
+}
+
+// Execute 熔断时直接返回 ErrBreakerOpen, 否则执行 fn 并记录结果, 返回 fn 的错误
+// fn panic 时计为失败
+func (b *Breaker) Execute(fn func() error) error {
+	generation, err := b.allow()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			b.done(generation, false)
+			panic(p)
+		}
+	}()
+	err = fn()
+	b.done(generation, !b.isFailure(err))
+	return err
+}
+
+// State 当前的状态
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	from := b.state
+	to := b.currentState(b.clock.Now())
+	b.mu.Unlock()
+	b.notify(from, to)
+	return to
+}
+
+// allow 判断是否放行一个请求, 返回放行时的 generation
+func (b *Breaker) allow() (uint64, error) {
+	b.mu.Lock()
+	from := b.state
+	state := b.currentState(b.clock.Now())
+	generation := b.generation
+	var err error
+	switch state {
+	case StateOpen:
+		err = ErrBreakerOpen
+	case StateHalfOpen:
+		if b.halfOpenInFlight >= b.halfOpenRequests {
+			err = ErrBreakerOpen
+		} else {
+			b.halfOpenInFlight++
+		}
+	}
+	b.mu.Unlock()
+	b.notify(from, state)
+	return generation, err
+}
+
+// done 记录请求的结果
+func (b *Breaker) done(generation uint64, success bool) {
+	b.mu.Lock()
+	now := b.clock.Now()
+	from := b.state
+	if generation == b.generation {
+		switch b.state {
+		case StateClosed:
+			b.record(now, success)
+			if b.shouldTrip(now) {
+				b.setState(StateOpen, now)
+			}
+		case StateHalfOpen:
+			b.halfOpenInFlight--
+			if !success {
+				b.setState(StateOpen, now)
+			} else if b.halfOpenSuccesses++; b.halfOpenSuccesses >= b.halfOpenRequests {
+				b.setState(StateClosed, now)
+			}
+		}
+	}
+	to := b.state
+	b.mu.Unlock()
+	b.notify(from, to)
+}
+
+// currentState 熔断超过 openTimeout 后进入半开状态
+func (b *Breaker) currentState(now time.Time) State {
+	if b.state == StateOpen && !now.Before(b.openedAt.Add(b.openTimeout)) {
+		b.setState(StateHalfOpen, now)
+	}
+	return b.state
+}
+
+func (b *Breaker) setState(state State, now time.Time) {
+	b.state = state
+	b.generation++
+	b.openedAt = now
+	b.consecutive = 0
+	b.halfOpenInFlight = 0
+	b.halfOpenSuccesses = 0
+	for i := range b.buckets {
+		b.buckets[i] = breakerBucket{}
+	}
+}
+
+func (b *Breaker) notify(from, to State) {
+	if from != to && b.onStateChange != nil {
+		b.onStateChange(from, to)
+	}
+}
+
+func (b *Breaker) record(now time.Time, success bool) {
+	index := now.UnixNano() / int64(b.bucketDuration)
+	bucket := &b.buckets[index%int64(len(b.buckets))]
+	if bucket.index != index {
+		*bucket = breakerBucket{index: index}
+	}
+	if success {
+		bucket.successes++
+		b.consecutive = 0
+	} else {
+		bucket.failures++
+		b.consecutive++
+	}
+}
+
+func (b *Breaker) shouldTrip(now time.Time) bool {
+	if b.maxConsecutive > 0 && b.consecutive >= b.maxConsecutive {
+		return true
+	}
+	if b.failureRatio <= 0 {
+		return false
+	}
+	// 只统计窗口内的时间桶
+	oldest := now.UnixNano()/int64(b.bucketDuration) - int64(len(b.buckets)) + 1
+	var total, failures int
+	for _, bucket := range b.buckets {
+		if bucket.index >= oldest {
+			total += bucket.successes + bucket.failures
+			failures += bucket.failures
+		}
+	}
+	return total > 0 && total >= b.minRequests && float64(failures)/float64(total) >= b.failureRatio
+}
@@ -0,0 +1,165 @@
+package limiter
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var errDownstream = errors.New("downstream failed")
+
+func newTestBreaker(clock *FakeClock, opts ...BreakerOption) *Breaker {
+	return NewBreaker(append(opts, WithClock(clock))...)
+}
+
+func execute(b *Breaker, fail bool) error {
+	return b.Execute(func() error {
+		if fail {
+			return errDownstream
+		}
+		return nil
+	})
+}
+
+func TestBreaker_ConsecutiveFailures(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1000, 0))
+	b := newTestBreaker(clock, WithFailureRatio(0, 0), WithConsecutiveFailures(3))
+	for i, fail := range []bool{true, true, false, true, true} {
+		if err := execute(b, fail); fail && err != errDownstream {
+			t.Errorf("call %d: err = %v want %v", i, err, errDownstream)
+		}
+	}
+	if s := b.State(); s != StateClosed {
+		t.Fatalf("State() = %v want %v", s, StateClosed)
+	}
+	_ = execute(b, true)
+	if s := b.State(); s != StateOpen {
+		t.Fatalf("State() = %v want %v", s, StateOpen)
+	}
+	if err := execute(b, false); err != ErrBreakerOpen {
+		t.Errorf("call when open: err = %v want %v", err, ErrBreakerOpen)
+	}
+}
+
+func TestBreaker_FailureRatio(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1000, 0))
+	b := newTestBreaker(clock, WithFailureRatio(0.5, 4), WithBreakerWindow(4*time.Second, 4))
+
+	// 请求数不足 minRequests 时不熔断
+	for i := 0; i < 3; i++ {
+		_ = execute(b, true)
+	}
+	if s := b.State(); s != StateClosed {
+		t.Fatalf("State() = %v want %v", s, StateClosed)
+	}
+	// 早期的失败滑出窗口
+	clock.Advance(4 * time.Second)
+	for _, fail := range []bool{false, false, true} {
+		_ = execute(b, fail)
+	}
+	if s := b.State(); s != StateClosed {
+		t.Fatalf("State() after window rolled = %v want %v", s, StateClosed)
+	}
+	clock.Advance(time.Second)
+	_ = execute(b, true)
+	if s := b.State(); s != StateOpen {
+		t.Errorf("State() = %v want %v", s, StateOpen)
+	}
+}
+
+func TestBreaker_HalfOpen(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1000, 0))
+	var changes []State
+	b := newTestBreaker(clock,
+		WithConsecutiveFailures(1),
+		WithOpenTimeout(time.Second),
+		WithHalfOpenRequests(2),
+		WithStateChange(func(from, to State) {
+			changes = append(changes, to)
+		}))
+
+	_ = execute(b, true)
+	clock.Advance(time.Second)
+	if s := b.State(); s != StateHalfOpen {
+		t.Fatalf("State() = %v want %v", s, StateHalfOpen)
+	}
+	// 半开状态下探测失败, 重新熔断
+	_ = execute(b, true)
+	if err := execute(b, false); err != ErrBreakerOpen {
+		t.Errorf("call after failed probe: err = %v want %v", err, ErrBreakerOpen)
+	}
+
+	// 半开状态下最多同时放行 2 个请求, 全部成功后恢复
+	clock.Advance(time.Second)
+	var inner error
+	err := b.Execute(func() error {
+		inner = b.Execute(func() error {
+			if err := execute(b, false); err != ErrBreakerOpen {
+				t.Errorf("third probe: err = %v want %v", err, ErrBreakerOpen)
+			}
+			return nil
+		})
+		return nil
+	})
+	if err != nil || inner != nil {
+		t.Fatalf("probes: %v, %v", err, inner)
+	}
+	if s := b.State(); s != StateClosed {
+		t.Errorf("State() = %v want %v", s, StateClosed)
+	}
+
+	want := []State{StateOpen, StateHalfOpen, StateOpen, StateHalfOpen, StateClosed}
+	if len(changes) != len(want) {
+		t.Fatalf("state changes = %v want %v", changes, want)
+	}
+	for i := range want {
+		if changes[i] != want[i] {
+			t.Errorf("state changes = %v want %v", changes, want)
+			break
+		}
+	}
+}
+
+func TestBreaker_StaleResult(t *testing.T) {
+	clock := NewFakeClock(time.Unix(1000, 0))
+	b := newTestBreaker(clock, WithConsecutiveFailures(1), WithOpenTimeout(time.Second))
+	// 熔断前发出的请求在熔断后才失败, 不影响半开状态
+	_ = b.Execute(func() error {
+		_ = execute(b, true)
+		clock.Advance(time.Second)
+		if s := b.State(); s != StateHalfOpen {
+			t.Errorf("State() = %v want %v", s, StateHalfOpen)
+		}
+		return errDownstream
+	})
+	if s := b.State(); s != StateHalfOpen {
+		t.Errorf("State() after stale failure = %v want %v", s, StateHalfOpen)
+	}
+}
+
+func TestBreakerUnaryClientInterceptor(t *testing.T) {
+	b := NewBreaker(WithConsecutiveFailures(2), WithIsFailure(IsServerError))
+	interceptor := BreakerUnaryClientInterceptor(b)
+	invoke := func(code codes.Code) error {
+		return interceptor(context.Background(), "/svc/Method", nil, nil, nil,
+			func(context.Context, string, interface{}, interface{}, *grpc.ClientConn, ...grpc.CallOption) error {
+				return status.Error(code, code.String())
+			})
+	}
+
+	for i := 0; i < 3; i++ {
+		if err := invoke(codes.NotFound); status.Code(err) != codes.NotFound {
+			t.Errorf("call %d: err = %v want %v", i, err, codes.NotFound)
+		}
+	}
+	_ = invoke(codes.Unavailable)
+	_ = invoke(codes.Unavailable)
+	if err := invoke(codes.OK); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("call when open: err = %v want %v", err, codes.ResourceExhausted)
+	}
+}
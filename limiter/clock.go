@@ -0,0 +1,142 @@
+package limiter
+
+import (
+	"sync"
+	"time"
+)
+
+type (
+	// Clock 限速器使用的时钟, 默认为系统时钟, 测试时可以使用 FakeClock
+	Clock interface {
+		Now() time.Time
+		NewTimer(d time.Duration) Timer
+	}
+
+	// Timer 对应 *time.Timer
+	Timer interface {
+		C() <-chan time.Time
+		Stop() bool
+	}
+
+	realClock struct{}
+
+	realTimer struct {
+		*time.Timer
+	}
+
+	// FakeClock 只在调用 Advance 或 Set 时前进的时钟, 用于编写确定性的测试
+	FakeClock struct {
+		mu     sync.Mutex
+		now    time.Time
+		timers []*fakeTimer
+		// added 有新的 Timer 时通知 WaitForTimers
+		added chan struct{}
+	}
+
+	fakeTimer struct {
+		clock *FakeClock
+		at    time.Time
+		c     chan time.Time
+	}
+)
+
+// defaultClock 系统时钟
+var defaultClock Clock = realClock{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) NewTimer(d time.Duration) Timer {
+	return realTimer{time.NewTimer(d)}
+}
+
+func (t realTimer) C() <-chan time.Time {
+	return t.Timer.C
+}
+
+// NewFakeClock 实例化一个从 now 开始的 FakeClock
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{
+		now:   now,
+		added: make(chan struct{}, 1),
+	}
+}
+
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// NewTimer d 小于等于 0 时 Timer 立即触发
+func (c *FakeClock) NewTimer(d time.Duration) Timer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t := &fakeTimer{clock: c, at: c.now.Add(d), c: make(chan time.Time, 1)}
+	if d <= 0 {
+		t.c <- c.now
+		return t
+	}
+	c.timers = append(c.timers, t)
+	select {
+	case c.added <- struct{}{}:
+	default:
+	}
+	return t
+}
+
+// Advance 时钟前进 d, 触发到期的 Timer
+func (c *FakeClock) Advance(d time.Duration) {
+	c.Set(c.Now().Add(d))
+}
+
+// Set 将时钟设置为 now, 触发到期的 Timer, 时钟不会回退
+func (c *FakeClock) Set(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if now.Before(c.now) {
+		return
+	}
+	c.now = now
+	pending := c.timers[:0]
+	for _, t := range c.timers {
+		if t.at.After(now) {
+			pending = append(pending, t)
+			continue
+		}
+		t.c <- now
+	}
+	c.timers = pending
+}
+
+// Timers 尚未触发也未停止的 Timer 数量
+func (c *FakeClock) Timers() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.timers)
+}
+
+// WaitForTimers 阻塞直到至少有 n 个尚未触发的 Timer, 用于等待被测代码开始等待后再调用 Advance
+func (c *FakeClock) WaitForTimers(n int) {
+	for c.Timers() < n {
+		<-c.added
+	}
+}
+
+func (t *fakeTimer) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *fakeTimer) Stop() bool {
+	c := t.clock
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, pending := range c.timers {
+		if pending == t {
+			c.timers = append(c.timers[:i], c.timers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
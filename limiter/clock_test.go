@@ -0,0 +1,70 @@
+package limiter
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFakeClock(t *testing.T) {
+	clock := NewFakeClock(t0)
+	if now := clock.Now(); !now.Equal(t0) {
+		t.Fatalf("Now() = %v want %v", now, t0)
+	}
+
+	early := clock.NewTimer(d)
+	late := clock.NewTimer(3 * d)
+	stopped := clock.NewTimer(d)
+	if !stopped.Stop() {
+		t.Errorf("Stop() on pending timer = false want true")
+	}
+	if n := clock.Timers(); n != 2 {
+		t.Errorf("Timers() = %d want 2", n)
+	}
+
+	clock.Advance(2 * d)
+	select {
+	case at := <-early.C():
+		if !at.Equal(t2) {
+			t.Errorf("timer fired at %v want %v", at, t2)
+		}
+	default:
+		t.Errorf("timer should fire after Advance")
+	}
+	select {
+	case <-late.C():
+		t.Errorf("timer fired before its time")
+	case <-stopped.C():
+		t.Errorf("stopped timer fired")
+	default:
+	}
+	if early.Stop() {
+		t.Errorf("Stop() on fired timer = true want false")
+	}
+
+	// 时钟不回退
+	clock.Set(t0)
+	if now := clock.Now(); !now.Equal(t2) {
+		t.Errorf("Now() after Set to the past = %v want %v", now, t2)
+	}
+
+	select {
+	case <-clock.NewTimer(0).C():
+	default:
+		t.Errorf("timer with zero duration should fire immediately")
+	}
+	clock.WaitForTimers(1)
+	clock.Set(t3)
+	select {
+	case <-late.C():
+	default:
+		t.Errorf("timer should fire after Set")
+	}
+}
+
+func TestRealClock(t *testing.T) {
+	timer := defaultClock.NewTimer(time.Millisecond)
+	<-timer.C()
+	if timer.Stop() {
+		t.Errorf("Stop() on fired timer = true want false")
+	}
+}
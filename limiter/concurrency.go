@@ -0,0 +1,160 @@
+package limiter
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+var (
+	ErrQueueFull    = errors.New("limiter: concurrency wait queue is full")
+	ErrQueueTimeout = errors.New("limiter: timed out waiting in concurrency queue")
+)
+
+type (
+	// ConcurrencyLimiter 并发限制(舱壁), 同时最多 limit 个调用方持有许可
+	// 许可用完时最多 maxQueue 个调用方按先后顺序排队等待, 最多等待 queueTimeout
+	ConcurrencyLimiter struct {
+		mu           sync.Mutex
+		limit        int
+		inFlight     int
+		maxQueue     int
+		queueTimeout time.Duration
+		// waiters 排队等待的调用方, 头部最先得到许可
+		waiters *list.List
+		clock   Clock
+	}
+
+	waiter struct {
+		ready   chan struct{}
+		granted bool
+	}
+)
+
+// NewConcurrencyLimiter 实例化一个并发限制器, 默认不排队, 许可用完时 Acquire 直接返回 ErrQueueFull
+func NewConcurrencyLimiter(limit int, opts ...ConcurrencyOption) *ConcurrencyLimiter {
+	op := applyConcurrencyOptions(opts)
+	return &ConcurrencyLimiter{
+		limit:        limit,
+		maxQueue:     op.maxQueue,
+		queueTimeout: op.queueTimeout,
+		waiters:      list.New(),
+		clock:        op.clock,
+	}
+}
+
+func applyConcurrencyOptions(opts []ConcurrencyOption) concurrencyOptions {
+	var op concurrencyOptions
+	for _, opt := range opts {
+		opt.applyConcurrency(&op)
+	}
+	op.setDefaults()
+	return op
+}
+
+// Acquire 获取一个许可, 成功后必须调用 Release 归还
+// 排队已满时返回 ErrQueueFull, 排队超时返回 ErrQueueTimeout, ctx 结束时返回 ctx.Err()
+func (cl *ConcurrencyLimiter) Acquire(ctx context.Context) error {
+	cl.mu.Lock()
+	if cl.inFlight < cl.limit && cl.waiters.Len() == 0 {
+		cl.inFlight++
+		cl.mu.Unlock()
+		return nil
+	}
+	if cl.waiters.Len() >= cl.maxQueue {
+		cl.mu.Unlock()
+		return ErrQueueFull
+	}
+	w := &waiter{ready: make(chan struct{})}
+	elem := cl.waiters.PushBack(w)
+	cl.mu.Unlock()
+
+	var timeout <-chan time.Time
+	if cl.queueTimeout > 0 {
+		t := cl.clock.NewTimer(cl.queueTimeout)
+		defer t.Stop()
+		timeout = t.C()
+	}
+	var err error
+	select {
+	case <-w.ready:
+		return nil
+	case <-timeout:
+		err = ErrQueueTimeout
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	if w.granted {
+		// 超时的同时得到了许可, 转交给下一个调用方
+		cl.release()
+	} else {
+		cl.waiters.Remove(elem)
+	}
+	return err
+}
+
+// TryAcquire 不等待地获取一个许可, 成功后必须调用 Release 归还
+func (cl *ConcurrencyLimiter) TryAcquire() bool {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	if cl.inFlight < cl.limit && cl.waiters.Len() == 0 {
+		cl.inFlight++
+		return true
+	}
+	return false
+}
+
+// Release 归还一个许可, 有调用方在排队时直接转交给最早排队的调用方
+func (cl *ConcurrencyLimiter) Release() {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	cl.release()
+}
+
+func (cl *ConcurrencyLimiter) release() {
+	cl.inFlight--
+	cl.grant()
+}
+
+// grant 在未达到 limit 时依次唤醒排队的调用方
+func (cl *ConcurrencyLimiter) grant() {
+	for cl.inFlight < cl.limit && cl.waiters.Len() > 0 {
+		w := cl.waiters.Remove(cl.waiters.Front()).(*waiter)
+		w.granted = true
+		close(w.ready)
+		cl.inFlight++
+	}
+}
+
+func (cl *ConcurrencyLimiter) Limit() int {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	return cl.limit
+}
+
+// SetLimit 修改并发上限, 调大时立即唤醒排队的调用方, 调小时已持有的许可在归还后生效
+func (cl *ConcurrencyLimiter) SetLimit(limit int) {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	cl.limit = limit
+	cl.grant()
+}
+
+// InFlight 当前持有许可的调用方数量
+func (cl *ConcurrencyLimiter) InFlight() int {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	return cl.inFlight
+}
+
+// Queued 当前排队等待的调用方数量
+func (cl *ConcurrencyLimiter) Queued() int {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	return cl.waiters.Len()
+}
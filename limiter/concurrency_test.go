@@ -0,0 +1,121 @@
+package limiter
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestConcurrencyLimiter_Acquire(t *testing.T) {
+	cl := NewConcurrencyLimiter(2)
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		if err := cl.Acquire(ctx); err != nil {
+			t.Fatalf("Acquire %d: %v", i, err)
+		}
+	}
+	if err := cl.Acquire(ctx); err != ErrQueueFull {
+		t.Errorf("Acquire without queue = %v want %v", err, ErrQueueFull)
+	}
+	if cl.TryAcquire() {
+		t.Errorf("TryAcquire should fail when limit is reached")
+	}
+	cl.Release()
+	if !cl.TryAcquire() {
+		t.Errorf("TryAcquire should succeed after Release")
+	}
+	if n := cl.InFlight(); n != 2 {
+		t.Errorf("InFlight() = %d want 2", n)
+	}
+}
+
+func TestConcurrencyLimiter_Queue(t *testing.T) {
+	cl := NewConcurrencyLimiter(1, WithMaxQueue(2))
+	ctx := context.Background()
+	if err := cl.Acquire(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// 按排队顺序得到许可
+	order := make(chan int, 2)
+	for i := 0; i < 2; i++ {
+		go func(i int) {
+			if err := cl.Acquire(ctx); err != nil {
+				t.Errorf("queued Acquire %d: %v", i, err)
+				return
+			}
+			order <- i
+		}(i)
+		waitQueued(t, cl, i+1)
+	}
+	if err := cl.Acquire(ctx); err != ErrQueueFull {
+		t.Errorf("Acquire on full queue = %v want %v", err, ErrQueueFull)
+	}
+
+	for want := 0; want < 2; want++ {
+		cl.Release()
+		if got := <-order; got != want {
+			t.Errorf("waiter %d got permit, want %d", got, want)
+		}
+	}
+	if n, q := cl.InFlight(), cl.Queued(); n != 1 || q != 0 {
+		t.Errorf("InFlight, Queued = %d, %d want 1, 0", n, q)
+	}
+}
+
+func TestConcurrencyLimiter_Timeout(t *testing.T) {
+	clock := NewFakeClock(t0)
+	cl := NewConcurrencyLimiter(1, WithMaxQueue(1), WithQueueTimeout(d), WithClock(clock))
+	if err := cl.Acquire(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := waitAfter(t, clock, d, func() error {
+		return cl.Acquire(context.Background())
+	}); err != ErrQueueTimeout {
+		t.Errorf("Acquire = %v want %v", err, ErrQueueTimeout)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := cl.Acquire(ctx); err != context.Canceled {
+		t.Errorf("Acquire with canceled ctx = %v want %v", err, context.Canceled)
+	}
+	if n, q := cl.InFlight(), cl.Queued(); n != 1 || q != 0 {
+		t.Errorf("InFlight, Queued = %d, %d want 1, 0", n, q)
+	}
+}
+
+func TestConcurrencyLimiter_SetLimit(t *testing.T) {
+	cl := NewConcurrencyLimiter(1, WithMaxQueue(1))
+	ctx := context.Background()
+	if err := cl.Acquire(ctx); err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- cl.Acquire(ctx)
+	}()
+	waitQueued(t, cl, 1)
+	cl.SetLimit(2)
+	if err := <-done; err != nil {
+		t.Errorf("queued Acquire after SetLimit: %v", err)
+	}
+
+	// 调小后已持有的许可归还时不再转交
+	cl.SetLimit(1)
+	cl.Release()
+	if cl.TryAcquire() {
+		t.Errorf("TryAcquire should fail while in flight exceeds the new limit")
+	}
+}
+
+func waitQueued(t *testing.T, cl *ConcurrencyLimiter, n int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for cl.Queued() != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("Queued() = %d want %d", cl.Queued(), n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,112 @@
+package limiter
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+
+	"github.com/Fighting2520/go-common/ymlx"
+)
+
+// ConfigBinder 从 ymlx 配置读取 Limiter 的 limit 和 burst, 配置文件变化时调整正在使用的 Limiter
+// 配置格式如下, limit 为每秒事件数, 可以写 inf 表示不限速, 缺少的项保持 Limiter 当前的值
+//
+//	<prefix>:
+//	  <name>:
+//	    limit: 10
+//	    burst: 20
+type ConfigBinder struct {
+	mu       sync.Mutex
+	cfg      ymlx.YMLConfiger
+	prefix   string
+	limiters map[string]*Limiter
+}
+
+// NewConfigBinder 实例化一个 ConfigBinder, 配置变化需要 cfg 已经调用 ConfigFileChangeListen 开始监听
+// cfg 没有实现 ymlx.ChangeNotifier 时不会自动调整, 需要调用方自行调用 Reload
+func NewConfigBinder(cfg ymlx.YMLConfiger, prefix string) *ConfigBinder {
+	b := &ConfigBinder{
+		cfg:      cfg,
+		prefix:   prefix,
+		limiters: make(map[string]*Limiter),
+	}
+	if n, ok := cfg.(ymlx.ChangeNotifier); ok {
+		n.OnConfigChange(b.Reload)
+	}
+	return b
+}
+
+// Bind 按 name 的配置调整 lim, 之后配置变化时同步调整, 重复 Bind 同一个 name 时替换之前的 Limiter
+func (b *ConfigBinder) Bind(name string, lim *Limiter) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.limiters[name] = lim
+	b.apply(name, lim)
+}
+
+// Limiter 按 name 的配置创建一个 Limiter 并 Bind, 缺少配置时使用 r 和 burst
+func (b *ConfigBinder) Limiter(name string, r Limit, burst int, opts ...OptFn) *Limiter {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	// 按配置创建, 新的 Limiter 以配置的 burst 装满令牌
+	key := b.key(name)
+	if limit, ok := parseLimit(b.cfg.Get(key + ".limit")); ok {
+		r = limit
+	}
+	if n, ok := parseBurst(b.cfg.Get(key + ".burst")); ok {
+		burst = n
+	}
+	lim := NewLimiter(r, burst, opts...)
+	b.limiters[name] = lim
+	return lim
+}
+
+// Reload 重新读取所有已绑定的 Limiter 的配置, 已经积累的令牌保持不变
+func (b *ConfigBinder) Reload() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for name, lim := range b.limiters {
+		b.apply(name, lim)
+	}
+}
+
+func (b *ConfigBinder) apply(name string, lim *Limiter) {
+	key := b.key(name)
+	if limit, ok := parseLimit(b.cfg.Get(key + ".limit")); ok && limit != lim.Limit() {
+		lim.SetLimit(limit)
+	}
+	if burst, ok := parseBurst(b.cfg.Get(key + ".burst")); ok && burst != lim.Burst() {
+		lim.SetBurst(burst)
+	}
+}
+
+func (b *ConfigBinder) key(name string) string {
+	if b.prefix == "" {
+		return name
+	}
+	return b.prefix + "." + name
+}
+
+// parseLimit ymlx 的缓存不区分类型, 同一个 key 只能用 Get 读取原始值再转换
+func parseLimit(v interface{}) (Limit, bool) {
+	if v == nil {
+		return 0, false
+	}
+	f, err := strconv.ParseFloat(fmt.Sprint(v), 64)
+	if err != nil || math.IsNaN(f) {
+		return 0, false
+	}
+	if math.IsInf(f, 1) {
+		return Inf, true
+	}
+	return Limit(f), true
+}
+
+func parseBurst(v interface{}) (int, bool) {
+	if v == nil {
+		return 0, false
+	}
+	burst, err := strconv.Atoi(fmt.Sprint(v))
+	return burst, err == nil
+}
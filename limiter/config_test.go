@@ -0,0 +1,148 @@
+package limiter
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Fighting2520/go-common/ymlx"
+)
+
+// fakeConfig 只实现 ConfigBinder 用到的方法, change 模拟配置文件变化
+type fakeConfig struct {
+	ymlx.YMLConfiger
+	mu     sync.Mutex
+	values map[string]interface{}
+	hooks  []func()
+}
+
+func (c *fakeConfig) Get(keyName string) interface{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.values[keyName]
+}
+
+func (c *fakeConfig) OnConfigChange(fn func()) {
+	c.hooks = append(c.hooks, fn)
+}
+
+func (c *fakeConfig) change(values map[string]interface{}) {
+	c.mu.Lock()
+	c.values = values
+	c.mu.Unlock()
+	for _, fn := range c.hooks {
+		fn()
+	}
+}
+
+func TestConfigBinder(t *testing.T) {
+	cfg := &fakeConfig{values: map[string]interface{}{
+		"limiter.api.limit": 10,
+		"limiter.api.burst": 3,
+	}}
+	b := NewConfigBinder(cfg, "limiter")
+	clock := NewFakeClock(t0)
+	api := b.Limiter("api", 1, 1, WithClock(clock))
+	other := b.Limiter("other", 1, 1, WithClock(clock))
+	if api.Limit() != 10 || api.Burst() != 3 {
+		t.Errorf("api = %v, %d want 10, 3", api.Limit(), api.Burst())
+	}
+	if other.Limit() != 1 || other.Burst() != 1 {
+		t.Errorf("other without config = %v, %d want 1, 1", other.Limit(), other.Burst())
+	}
+
+	run(t, api, []allow{{t0, 2, true}})
+	cfg.change(map[string]interface{}{
+		"limiter.api.limit":   "0.5",
+		"limiter.api.burst":   5,
+		"limiter.other.limit": "inf",
+	})
+	if api.Limit() != 0.5 || api.Burst() != 5 {
+		t.Errorf("api after reload = %v, %d want 0.5, 5", api.Limit(), api.Burst())
+	}
+	if other.Limit() != Inf || other.Burst() != 1 {
+		t.Errorf("other after reload = %v, %d want Inf, 1", other.Limit(), other.Burst())
+	}
+	// 积累的令牌保持不变, 之前剩余 1 个
+	run(t, api, []allow{{t0, 1, true}, {t0, 1, false}})
+}
+
+func TestConfigBinder_Invalid(t *testing.T) {
+	cfg := &fakeConfig{values: map[string]interface{}{
+		"api.limit": "fast",
+		"api.burst": 2.5,
+	}}
+	lim := NewLimiter(10, 1)
+	NewConfigBinder(cfg, "").Bind("api", lim)
+	if lim.Limit() != 10 || lim.Burst() != 1 {
+		t.Errorf("lim with invalid config = %v, %d want 10, 1", lim.Limit(), lim.Burst())
+	}
+}
+
+func TestConfigBinder_YMLX(t *testing.T) {
+	cfg := ymlx.CreateYamlFactoryFromReader(strings.NewReader(`
+limiter:
+  api:
+    limit: 100
+    burst: 20
+`), "limiter_config_test.")
+	lim := NewConfigBinder(cfg, "limiter").Limiter("api", 1, 1)
+	if lim.Limit() != 100 || lim.Burst() != 20 {
+		t.Errorf("lim = %v, %d want 100, 20", lim.Limit(), lim.Burst())
+	}
+}
+
+// staticConfig 没有实现 ymlx.ChangeNotifier 的配置
+type staticConfig struct {
+	ymlx.YMLConfiger
+	values map[string]interface{}
+}
+
+func (c *staticConfig) Get(keyName string) interface{} {
+	return c.values[keyName]
+}
+
+func TestConfigBinder_ManualReload(t *testing.T) {
+	cfg := &staticConfig{values: map[string]interface{}{"api.limit": 10}}
+	b := NewConfigBinder(cfg, "")
+	lim := b.Limiter("api", 1, 1)
+	cfg.values = map[string]interface{}{"api.limit": 20}
+	b.Reload()
+	if lim.Limit() != 20 {
+		t.Errorf("lim after Reload = %v want 20", lim.Limit())
+	}
+}
+
+func TestConfigBinder_FileChange(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "config.yml")
+	write := func(limit int) {
+		content := "limiter:\n  api:\n    limit: " + strconv.Itoa(limit) + "\n    burst: 5\n"
+		if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(10)
+
+	// ymlx 的缓存是全局的, 每次使用不同的前缀
+	cfg := ymlx.CreateYamlFactory(dir, dir+".")
+	cfg.ConfigFileChangeListen()
+	lim := NewConfigBinder(cfg, "limiter").Limiter("api", 1, 1)
+	if lim.Limit() != 10 || lim.Burst() != 5 {
+		t.Fatalf("lim = %v, %d want 10, 5", lim.Limit(), lim.Burst())
+	}
+
+	// ymlx 会忽略 1 秒内的重复变化, 持续写入直到生效
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); {
+		write(20)
+		time.Sleep(100 * time.Millisecond)
+		if lim.Limit() == 20 {
+			return
+		}
+	}
+	t.Errorf("lim after file change = %v want 20", lim.Limit())
+}
@@ -0,0 +1,116 @@
+package limiter
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// GCRA 通用信元速率算法, 只记录理论到达时间, 效果与令牌桶相同
+type GCRA struct {
+	mu    sync.Mutex
+	limit Limit
+	burst int
+	// tat 理论到达时间, 即按 limit 匀速发生时下一个事件的时间
+	tat   time.Time
+	clock Clock
+}
+
+// NewGCRA 实例化一个 GCRA 限速器, 最多允许 b 个事件突发
+// limit 小于等于 0 时不允许任何事件
+func NewGCRA(r Limit, b int, opts ...OptFn) *GCRA {
+	op := applyOptions(options{}, opts)
+	return &GCRA{
+		limit: r,
+		burst: b,
+		clock: op.clock,
+	}
+}
+
+func (g *GCRA) Allow() bool {
+	return g.AllowN(g.clock.Now(), 1)
+}
+
+func (g *GCRA) AllowN(now time.Time, n int) bool {
+	return g.reserveN(now, n, 0).ok
+}
+
+func (g *GCRA) Reserve() *Reservation {
+	return g.ReserveN(g.clock.Now(), 1)
+}
+
+func (g *GCRA) ReserveN(now time.Time, n int) *Reservation {
+	r := g.reserveN(now, n, InfDuration)
+	return &r
+}
+
+func (g *GCRA) Wait(ctx context.Context) error {
+	return g.WaitN(ctx, 1)
+}
+
+func (g *GCRA) WaitN(ctx context.Context, n int) error {
+	g.mu.Lock()
+	limit := g.limit
+	burst := g.burst
+	g.mu.Unlock()
+
+	if n > burst && limit != Inf {
+		return fmt.Errorf("rate: Wait(n=%d) exceeds limiter's burst %d", n, burst)
+	}
+	return waitN(ctx, n, g.clock, g.reserveN)
+}
+
+func (g *GCRA) reserveN(now time.Time, n int, maxFutureReserve time.Duration) Reservation {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.limit == Inf || n <= 0 {
+		return Reservation{ok: true, tokens: n, timeToAct: now, clock: g.clock}
+	}
+	if g.limit <= 0 || n > g.burst {
+		return Reservation{}
+	}
+
+	newTat, allowAt := g.allowAt(now, n)
+	var waitDuration time.Duration
+	if allowAt.After(now) {
+		waitDuration = allowAt.Sub(now)
+	}
+	if waitDuration > maxFutureReserve {
+		return Reservation{}
+	}
+
+	g.tat = newTat
+	limit := g.limit
+	timeToAct := now.Add(waitDuration)
+	return Reservation{
+		ok:        true,
+		tokens:    n,
+		timeToAct: timeToAct,
+		limit:     limit,
+		clock:     g.clock,
+		cancel: func(now time.Time) {
+			g.mu.Lock()
+			defer g.mu.Unlock()
+			if !timeToAct.After(now) {
+				return
+			}
+			g.tat = g.tat.Add(-limit.durationFromTokens(float64(n)))
+			if g.tat.Before(now) {
+				g.tat = now
+			}
+		},
+	}
+}
+
+// allowAt n 个事件最早可以发生的时间, newTat 为允许后新的理论到达时间, 不改变状态
+func (g *GCRA) allowAt(now time.Time, n int) (newTat, allowAt time.Time) {
+	tat := g.tat
+	if tat.Before(now) {
+		tat = now
+	}
+	newTat = tat.Add(g.limit.durationFromTokens(float64(n)))
+	// 最多可以提前 burst 个事件的时间发生
+	return newTat, newTat.Add(-g.limit.durationFromTokens(float64(g.burst)))
+}
@@ -0,0 +1,79 @@
+package limiter
+
+import (
+	"context"
+	"testing"
+)
+
+func TestGCRA_Burst1(t *testing.T) {
+	run(t, NewGCRA(10, 1), []allow{
+		{t0, 1, true},
+		{t0, 1, false},
+		{t1, 1, true},
+		{t1, 1, false},
+		{t2, 2, false}, // burst size is 1, so n=2 always fails
+		{t2, 1, true},
+		{t2, -10, true},
+		{t2, 1, false},
+	})
+}
+
+func TestGCRA_Burst3(t *testing.T) {
+	run(t, NewGCRA(10, 3), []allow{
+		{t0, 2, true},
+		{t0, 2, false},
+		{t0, 1, true},
+		{t0, 1, false},
+		{t1, 4, false},
+		{t2, 1, true},
+		{t3, 1, true},
+		{t4, 1, true},
+		{t4, 1, true},
+		{t4, 1, false},
+		{t9, 3, true},
+		{t9, 0, true},
+	})
+}
+
+func TestGCRA_ZeroLimit(t *testing.T) {
+	run(t, NewGCRA(0, 3), []allow{
+		{t0, 1, false},
+		{t9, 1, false},
+	})
+	run(t, NewGCRA(Inf, 0), []allow{
+		{t0, 10, true},
+	})
+}
+
+func TestGCRA_Reserve(t *testing.T) {
+	lim := NewGCRA(10, 1)
+	r1 := lim.ReserveN(t0, 1)
+	r2 := lim.ReserveN(t0, 1)
+	if d := r1.DelayFrom(t0); d != 0 {
+		t.Errorf("r1 delay = %v want 0", d)
+	}
+	if d := r2.DelayFrom(t0); d != d {
+		t.Errorf("r2 delay = %v want %v", d, d)
+	}
+	r2.CancelAt(t0)
+	if d := lim.ReserveN(t0, 1).DelayFrom(t0); d != d {
+		t.Errorf("delay after cancel = %v want %v", d, d)
+	}
+}
+
+func TestGCRA_Wait(t *testing.T) {
+	clock := NewFakeClock(t0)
+	lim := NewGCRA(10, 1, WithClock(clock))
+	ctx := context.Background()
+	if err := lim.WaitN(ctx, 2); err == nil {
+		t.Errorf("WaitN(2) with burst 1 should fail")
+	}
+	if err := lim.Wait(ctx); err != nil {
+		t.Fatalf("first Wait: %v", err)
+	}
+	if err := waitAfter(t, clock, d, func() error {
+		return lim.Wait(ctx)
+	}); err != nil {
+		t.Errorf("second Wait: %v", err)
+	}
+}
@@ -0,0 +1,161 @@
+package limiter
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// metadataRetryAfter 被限速时在响应头中返回需要等待的秒数
+const metadataRetryAfter = "retry-after"
+
+// GRPCKeyFunc 从请求上下文中提取限速的 key, 例如客户端 IP 或 metadata 中的 API key
+type GRPCKeyFunc = func(ctx context.Context, fullMethod string) string
+
+// PeerIP 以客户端连接的 IP 作为 key
+func PeerIP(ctx context.Context, _ string) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
+
+// UnaryServerInterceptor 所有请求共用 lim 限速, 超过限制时返回 codes.ResourceExhausted
+// 使用 WithConcurrency 时还会限制并发, 排队失败时同样返回 codes.ResourceExhausted, lim 为 nil 时只限制并发
+func UnaryServerInterceptor(lim RateLimiter, opts ...MiddlewareOptFn) grpc.UnaryServerInterceptor {
+	return unaryServerInterceptor(func(context.Context, string) RateLimiter {
+		return lim
+	}, opts)
+}
+
+// StreamServerInterceptor 每个流在建立时消耗一个事件, 超过限制时返回 codes.ResourceExhausted
+func StreamServerInterceptor(lim RateLimiter, opts ...MiddlewareOptFn) grpc.StreamServerInterceptor {
+	return streamServerInterceptor(func(context.Context, string) RateLimiter {
+		return lim
+	}, opts)
+}
+
+// KeyedUnaryServerInterceptor 按 key 函数提取的 key 分别限速
+func KeyedUnaryServerInterceptor(kl *KeyedLimiter, key GRPCKeyFunc, opts ...MiddlewareOptFn) grpc.UnaryServerInterceptor {
+	return unaryServerInterceptor(func(ctx context.Context, fullMethod string) RateLimiter {
+		return kl.Limiter(key(ctx, fullMethod))
+	}, opts)
+}
+
+// KeyedStreamServerInterceptor 按 key 函数提取的 key 分别限速
+func KeyedStreamServerInterceptor(kl *KeyedLimiter, key GRPCKeyFunc, opts ...MiddlewareOptFn) grpc.StreamServerInterceptor {
+	return streamServerInterceptor(func(ctx context.Context, fullMethod string) RateLimiter {
+		return kl.Limiter(key(ctx, fullMethod))
+	}, opts)
+}
+
+func unaryServerInterceptor(limiterOf func(ctx context.Context, fullMethod string) RateLimiter, opts []MiddlewareOptFn) grpc.UnaryServerInterceptor {
+	var op middlewareOptions
+	for _, fn := range opts {
+		fn(&op)
+	}
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if lim := limiterOf(ctx, info.FullMethod); lim != nil {
+			if ok, retryAfter := admit(lim); !ok {
+				if retryAfter > 0 {
+					_ = grpc.SetHeader(ctx, retryAfterMD(retryAfter))
+				}
+				return nil, exhausted(info.FullMethod)
+			}
+		}
+		if op.concurrency != nil {
+			if err := op.concurrency.Acquire(ctx); err != nil {
+				return nil, exhausted(info.FullMethod)
+			}
+			defer op.concurrency.Release()
+		}
+		return handler(ctx, req)
+	}
+}
+
+func streamServerInterceptor(limiterOf func(ctx context.Context, fullMethod string) RateLimiter, opts []MiddlewareOptFn) grpc.StreamServerInterceptor {
+	var op middlewareOptions
+	for _, fn := range opts {
+		fn(&op)
+	}
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if lim := limiterOf(ss.Context(), info.FullMethod); lim != nil {
+			if ok, retryAfter := admit(lim); !ok {
+				if retryAfter > 0 {
+					_ = ss.SetHeader(retryAfterMD(retryAfter))
+				}
+				return exhausted(info.FullMethod)
+			}
+		}
+		if op.concurrency != nil {
+			if err := op.concurrency.Acquire(ss.Context()); err != nil {
+				return exhausted(info.FullMethod)
+			}
+			defer op.concurrency.Release()
+		}
+		return handler(srv, ss)
+	}
+}
+
+func retryAfterMD(d time.Duration) metadata.MD {
+	return metadata.Pairs(metadataRetryAfter, strconv.Itoa(ceilSeconds(d)))
+}
+
+func exhausted(fullMethod string) error {
+	return status.Errorf(codes.ResourceExhausted, "%s is rejected by rate limiter, please retry later", fullMethod)
+}
+
+// IsServerError 判断 RPC 错误是否说明下游服务异常, 可作为 Breaker 的 WithIsFailure
+// 参数错误, 找不到资源等业务错误不计为失败
+func IsServerError(err error) bool {
+	switch status.Code(err) {
+	case codes.Unknown, codes.DeadlineExceeded, codes.ResourceExhausted,
+		codes.Internal, codes.Unavailable, codes.DataLoss:
+		return true
+	default:
+		return false
+	}
+}
+
+// BreakerUnaryClientInterceptor 使用熔断器保护客户端调用, 熔断时返回 codes.ResourceExhausted
+// 可以通过 grpcpool.WithDialOptions(grpc.WithChainUnaryInterceptor(...)) 用于连接池
+func BreakerUnaryClientInterceptor(b *Breaker) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{},
+		cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		err := b.Execute(func() error {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		})
+		if err == ErrBreakerOpen {
+			return status.Errorf(codes.ResourceExhausted, "%s: %v", method, err)
+		}
+		return err
+	}
+}
+
+// BreakerStreamClientInterceptor 使用熔断器保护建立流, 流建立后的错误不计入
+func BreakerStreamClientInterceptor(b *Breaker) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string,
+		streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		var stream grpc.ClientStream
+		err := b.Execute(func() (err error) {
+			stream, err = streamer(ctx, desc, cc, method, opts...)
+			return err
+		})
+		if err == ErrBreakerOpen {
+			return nil, status.Errorf(codes.ResourceExhausted, "%s: %v", method, err)
+		}
+		return stream, err
+	}
+}
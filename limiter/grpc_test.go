@@ -0,0 +1,98 @@
+package limiter
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func newHealthClient(t *testing.T, opts ...grpc.ServerOption) grpc_health_v1.HealthClient {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer(opts...)
+	grpc_health_v1.RegisterHealthServer(srv, health.NewServer())
+	go func() {
+		_ = srv.Serve(lis)
+	}()
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+	return grpc_health_v1.NewHealthClient(conn)
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	client := newHealthClient(t, grpc.UnaryInterceptor(UnaryServerInterceptor(NewLimiter(1, 1))))
+	ctx := context.Background()
+
+	if _, err := client.Check(ctx, &grpc_health_v1.HealthCheckRequest{}); err != nil {
+		t.Fatalf("first Check: %v", err)
+	}
+	var header metadata.MD
+	_, err := client.Check(ctx, &grpc_health_v1.HealthCheckRequest{}, grpc.Header(&header))
+	if code := status.Code(err); code != codes.ResourceExhausted {
+		t.Fatalf("second Check code = %v want %v", code, codes.ResourceExhausted)
+	}
+	if v := header.Get(metadataRetryAfter); len(v) != 1 || v[0] != "1" {
+		t.Errorf("%s = %v want [1]", metadataRetryAfter, v)
+	}
+}
+
+func TestStreamServerInterceptor(t *testing.T) {
+	client := newHealthClient(t, grpc.StreamInterceptor(StreamServerInterceptor(NewLimiter(1, 1))))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	first, err := client.Watch(ctx, &grpc_health_v1.HealthCheckRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := first.Recv(); err != nil {
+		t.Fatalf("first Watch: %v", err)
+	}
+	second, err := client.Watch(ctx, &grpc_health_v1.HealthCheckRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := second.Recv(); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("second Watch err = %v want %v", err, codes.ResourceExhausted)
+	}
+}
+
+func TestKeyedUnaryServerInterceptor(t *testing.T) {
+	byMethod := func(_ context.Context, fullMethod string) string {
+		return fullMethod
+	}
+	client := newHealthClient(t, grpc.UnaryInterceptor(KeyedUnaryServerInterceptor(NewKeyedLimiter(1, 1), byMethod)))
+	ctx := context.Background()
+	if _, err := client.Check(ctx, &grpc_health_v1.HealthCheckRequest{}); err != nil {
+		t.Fatalf("first Check: %v", err)
+	}
+	if _, err := client.Check(ctx, &grpc_health_v1.HealthCheckRequest{}); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("second Check err = %v want %v", err, codes.ResourceExhausted)
+	}
+}
+
+func TestPeerIP(t *testing.T) {
+	if key := PeerIP(context.Background(), ""); key != "" {
+		t.Errorf("PeerIP without peer = %q want empty", key)
+	}
+}
@@ -0,0 +1,105 @@
+package limiter
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	HeaderRetryAfter = "Retry-After"
+	HeaderLimit      = "X-RateLimit-Limit"
+	HeaderRemaining  = "X-RateLimit-Remaining"
+	HeaderReset      = "X-RateLimit-Reset"
+)
+
+// HTTPKeyFunc 从请求中提取限速的 key, 例如客户端 IP 或 API key
+type HTTPKeyFunc = func(r *http.Request) string
+
+// RemoteIP 以 r.RemoteAddr 中的 IP 作为 key
+// 服务在反向代理之后时, 应使用从可信代理设置的请求头中提取 IP 的 HTTPKeyFunc
+func RemoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// HTTPMiddleware 所有请求共用 lim 限速, 超过限制时返回 429
+// 使用 WithConcurrency 时还会限制并发, 排队失败时返回 503, lim 为 nil 时只限制并发
+func HTTPMiddleware(lim RateLimiter, opts ...MiddlewareOptFn) func(http.Handler) http.Handler {
+	return httpMiddleware(func(*http.Request) RateLimiter {
+		return lim
+	}, opts)
+}
+
+// KeyedHTTPMiddleware 按 key 函数提取的 key 分别限速, 超过限制时返回 429
+func KeyedHTTPMiddleware(kl *KeyedLimiter, key HTTPKeyFunc, opts ...MiddlewareOptFn) func(http.Handler) http.Handler {
+	return httpMiddleware(func(r *http.Request) RateLimiter {
+		return kl.Limiter(key(r))
+	}, opts)
+}
+
+func httpMiddleware(limiterOf func(r *http.Request) RateLimiter, opts []MiddlewareOptFn) func(http.Handler) http.Handler {
+	var op middlewareOptions
+	for _, fn := range opts {
+		fn(&op)
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if lim := limiterOf(r); lim != nil {
+				ok, retryAfter := admit(lim)
+				setQuotaHeaders(w.Header(), lim)
+				if !ok {
+					if retryAfter > 0 {
+						w.Header().Set(HeaderRetryAfter, strconv.Itoa(ceilSeconds(retryAfter)))
+					}
+					http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+					return
+				}
+			}
+			if op.concurrency != nil {
+				if err := op.concurrency.Acquire(r.Context()); err != nil {
+					http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+					return
+				}
+				defer op.concurrency.Release()
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// admit 判断是否允许一个事件, 不允许时不消耗令牌, 返回 false 和需要等待的时间
+// 无法计算或永远无法满足时 retryAfter 为 0
+func admit(lim RateLimiter) (ok bool, retryAfter time.Duration) {
+	if lim.Allow() {
+		return true, 0
+	}
+	if r, ok := lim.(retryReporter); ok {
+		if d := r.retryAfter(); d != InfDuration {
+			return false, d
+		}
+	}
+	return false, 0
+}
+
+// setQuotaHeaders lim 能够报告当前额度时设置 X-RateLimit-* 响应头
+func setQuotaHeaders(h http.Header, lim RateLimiter) {
+	q, ok := lim.(quotaReporter)
+	if !ok {
+		return
+	}
+	limit, remaining, reset := q.quota()
+	h.Set(HeaderLimit, strconv.Itoa(limit))
+	h.Set(HeaderRemaining, strconv.Itoa(remaining))
+	h.Set(HeaderReset, strconv.Itoa(ceilSeconds(reset)))
+}
+
+// ceilSeconds 向上取整的秒数
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,178 @@
+package limiter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func serve(h http.Handler, remoteAddr string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = remoteAddr
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+var okHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+})
+
+func TestHTTPMiddleware(t *testing.T) {
+	h := HTTPMiddleware(NewLimiter(1, 2))(okHandler)
+
+	for i, want := range []struct {
+		code             int
+		remaining, reset string
+	}{
+		{http.StatusOK, "1", "1"},
+		{http.StatusOK, "0", "2"},
+		{http.StatusTooManyRequests, "0", "2"},
+	} {
+		rec := serve(h, "10.0.0.1:1234")
+		if rec.Code != want.code {
+			t.Errorf("request %d: code = %d want %d", i, rec.Code, want.code)
+		}
+		if v := rec.Header().Get(HeaderLimit); v != "2" {
+			t.Errorf("request %d: %s = %q want 2", i, HeaderLimit, v)
+		}
+		if v := rec.Header().Get(HeaderRemaining); v != want.remaining {
+			t.Errorf("request %d: %s = %q want %s", i, HeaderRemaining, v, want.remaining)
+		}
+		if v := rec.Header().Get(HeaderReset); v != want.reset {
+			t.Errorf("request %d: %s = %q want %s", i, HeaderReset, v, want.reset)
+		}
+	}
+	if v := serve(h, "10.0.0.1:1234").Header().Get(HeaderRetryAfter); v != "1" {
+		t.Errorf("%s = %q want 1", HeaderRetryAfter, v)
+	}
+}
+
+// overlappingLimiter Reserve 等到所有调用方都预约后才返回, 模拟同时被拒绝的请求
+type overlappingLimiter struct {
+	*Limiter
+	reserved sync.WaitGroup
+}
+
+func (l *overlappingLimiter) Reserve() *Reservation {
+	r := l.Limiter.Reserve()
+	l.reserved.Done()
+	l.reserved.Wait()
+	return r
+}
+
+func TestHTTPMiddleware_OverlappingRejections(t *testing.T) {
+	clock := NewFakeClock(t0)
+	lim := &overlappingLimiter{Limiter: NewLimiter(1, 1, WithClock(clock))}
+	lim.AllowN(t0, 1)
+	h := HTTPMiddleware(lim)(okHandler)
+
+	// 同时被拒绝的请求不能消耗令牌
+	const requests = 2
+	lim.reserved.Add(requests)
+	var wg sync.WaitGroup
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rec := serve(h, "10.0.0.1:1234")
+			if rec.Code != http.StatusTooManyRequests {
+				t.Errorf("code = %d want %d", rec.Code, http.StatusTooManyRequests)
+			}
+			if v := rec.Header().Get(HeaderRetryAfter); v != "1" {
+				t.Errorf("%s = %q want 1", HeaderRetryAfter, v)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if !lim.AllowN(t0.Add(time.Second), 1) {
+		t.Errorf("rejected requests consumed tokens")
+	}
+}
+
+func TestHTTPMiddleware_NeverAllowed(t *testing.T) {
+	// 漏桶容量为 0, 不报告额度, 也无法给出等待时间
+	rec := serve(HTTPMiddleware(NewLeakyBucket(1, 0))(okHandler), "10.0.0.1:1234")
+	if rec.Code != http.StatusTooManyRequests {
+		t.Errorf("code = %d want %d", rec.Code, http.StatusTooManyRequests)
+	}
+	for _, key := range []string{HeaderRetryAfter, HeaderLimit} {
+		if v := rec.Header().Get(key); v != "" {
+			t.Errorf("%s = %q want empty", key, v)
+		}
+	}
+}
+
+func TestKeyedHTTPMiddleware(t *testing.T) {
+	h := KeyedHTTPMiddleware(NewKeyedLimiter(1, 1), RemoteIP)(okHandler)
+	for i, want := range []struct {
+		addr string
+		code int
+	}{
+		{"10.0.0.1:1234", http.StatusOK},
+		{"10.0.0.1:5678", http.StatusTooManyRequests}, // 同一 IP 的不同端口
+		{"10.0.0.2:1234", http.StatusOK},
+	} {
+		if rec := serve(h, want.addr); rec.Code != want.code {
+			t.Errorf("request %d from %s: code = %d want %d", i, want.addr, rec.Code, want.code)
+		}
+	}
+}
+
+func TestHTTPMiddleware_Concurrency(t *testing.T) {
+	cl := NewConcurrencyLimiter(1)
+	var inside http.HandlerFunc = func(w http.ResponseWriter, r *http.Request) {
+		// 处理请求期间持有许可, 并发请求被拒绝
+		if rec := serve(HTTPMiddleware(nil, WithConcurrency(cl))(okHandler), "10.0.0.1:1234"); rec.Code != http.StatusServiceUnavailable {
+			t.Errorf("concurrent request code = %d want %d", rec.Code, http.StatusServiceUnavailable)
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+	if rec := serve(HTTPMiddleware(nil, WithConcurrency(cl))(inside), "10.0.0.1:1234"); rec.Code != http.StatusOK {
+		t.Errorf("code = %d want %d", rec.Code, http.StatusOK)
+	}
+	if n := cl.InFlight(); n != 0 {
+		t.Errorf("InFlight() after request = %d want 0", n)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	clock := NewFakeClock(t0)
+	cases := []struct {
+		name string
+		lim  RateLimiter
+		want time.Duration
+	}{
+		{"Limiter", NewLimiter(Every(d), 1, WithClock(clock)), d},
+		{"GCRA", NewGCRA(Every(d), 1, WithClock(clock)), d},
+		{"LeakyBucket", NewLeakyBucket(Every(d), 2, WithClock(clock)), d},
+		{"SlidingWindowLog", NewSlidingWindowLog(1, d, WithClock(clock)), d},
+		{"MultiLimiter", NewMultiLimiter([]Tier{
+			{Name: "fast", Limiter: NewLimiter(Every(d), 1, WithClock(clock))},
+			{Name: "slow", Limiter: NewLimiter(Every(2*d), 1, WithClock(clock))},
+		}, WithClock(clock)), 2 * d},
+	}
+	for _, c := range cases {
+		now := clock.Now()
+		if !c.lim.AllowN(now, 1) {
+			t.Fatalf("%s: first AllowN = false want true", c.name)
+		}
+		r := c.lim.(retryReporter)
+		// 多次计算不改变状态
+		for i := 0; i < 3; i++ {
+			if got := r.retryAfter(); got != c.want {
+				t.Errorf("%s: retryAfter() = %v want %v", c.name, got, c.want)
+			}
+		}
+		if !c.lim.AllowN(now.Add(c.want), 1) {
+			t.Errorf("%s: AllowN after retryAfter = false want true", c.name)
+		}
+	}
+
+	if got := NewLimiter(0, 1).retryAfter(); got != InfDuration {
+		t.Errorf("zero Limit retryAfter() = %v want %v", got, InfDuration)
+	}
+}
@@ -0,0 +1,205 @@
+package limiter
+
+import (
+	"container/list"
+	"context"
+	"hash/fnv"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultMaxKeys KeyedLimiter 默认最多保存的 key 数量
+	defaultMaxKeys = 10240
+	// maxShards 分片数量上限, 每个分片至少保存 minShardKeys 个 key
+	maxShards    = 16
+	minShardKeys = 64
+)
+
+type (
+	// KeyedLimiter 按 key 限速, 例如每个用户或每个 IP 一个令牌桶
+	// 每个 key 的 Limiter 在第一次使用时创建, 超过 maxKeys 时淘汰最久未使用的 key
+	// 空闲超过 ttl 的 key 在访问同一分片时被淘汰
+	KeyedLimiter struct {
+		limit Limit
+		burst int
+		ttl   time.Duration
+		clock Clock
+
+		shards []*keyedShard
+
+		// overrides 单独设置了 limit 和 burst 的 key
+		omu       sync.RWMutex
+		overrides map[string]keyLimit
+	}
+
+	keyLimit struct {
+		limit Limit
+		burst int
+	}
+
+	// keyedShard 一个分片, lru 头部为最近使用的 key
+	keyedShard struct {
+		mu      sync.Mutex
+		maxKeys int
+		items   map[string]*list.Element
+		lru     *list.List
+	}
+
+	keyedEntry struct {
+		key      string
+		lim      *Limiter
+		lastUsed time.Time
+	}
+)
+
+// NewKeyedLimiter 实例化一个按 key 限速的限速器, 每个 key 默认的 limit 为 r, burst 为 b
+func NewKeyedLimiter(r Limit, b int, opts ...KeyedOption) *KeyedLimiter {
+	op := keyedOptions{maxKeys: defaultMaxKeys}
+	for _, opt := range opts {
+		opt.applyKeyed(&op)
+	}
+	op.setDefaults()
+	if op.maxKeys <= 0 {
+		op.maxKeys = defaultMaxKeys
+	}
+
+	n := maxShards
+	for n > 1 && op.maxKeys/n < minShardKeys {
+		n /= 2
+	}
+	kl := &KeyedLimiter{
+		limit:     r,
+		burst:     b,
+		ttl:       op.keyTTL,
+		clock:     op.clock,
+		shards:    make([]*keyedShard, n),
+		overrides: make(map[string]keyLimit),
+	}
+	for i := range kl.shards {
+		kl.shards[i] = &keyedShard{
+			maxKeys: (op.maxKeys + n - 1) / n,
+			items:   make(map[string]*list.Element),
+			lru:     list.New(),
+		}
+	}
+	for key, l := range op.keyLimits {
+		kl.overrides[key] = l
+	}
+	return kl
+}
+
+func (kl *KeyedLimiter) Allow(key string) bool {
+	return kl.AllowN(key, kl.clock.Now(), 1)
+}
+
+// AllowN 判断 key 在指定时间是否能够允许n个事件
+func (kl *KeyedLimiter) AllowN(key string, now time.Time, n int) bool {
+	return kl.get(key, now).AllowN(now, n)
+}
+
+func (kl *KeyedLimiter) Wait(ctx context.Context, key string) error {
+	return kl.WaitN(ctx, key, 1)
+}
+
+// WaitN 阻塞，直到 key 允许n个事件发生
+func (kl *KeyedLimiter) WaitN(ctx context.Context, key string, n int) error {
+	return kl.get(key, kl.clock.Now()).WaitN(ctx, n)
+}
+
+func (kl *KeyedLimiter) Reserve(key string) *Reservation {
+	now := kl.clock.Now()
+	return kl.get(key, now).ReserveN(now, 1)
+}
+
+// Limiter 返回 key 对应的 Limiter, 不存在时创建
+func (kl *KeyedLimiter) Limiter(key string) *Limiter {
+	return kl.get(key, kl.clock.Now())
+}
+
+// SetKeyLimit 单独设置 key 的 limit 和 burst, key 的 Limiter 已存在时立即生效
+func (kl *KeyedLimiter) SetKeyLimit(key string, r Limit, b int) {
+	kl.omu.Lock()
+	kl.overrides[key] = keyLimit{limit: r, burst: b}
+	kl.omu.Unlock()
+
+	s := kl.shard(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if elem, ok := s.items[key]; ok {
+		lim := elem.Value.(*keyedEntry).lim
+		lim.SetLimit(r)
+		lim.SetBurst(b)
+	}
+}
+
+// Len 当前保存的 key 数量
+func (kl *KeyedLimiter) Len() int {
+	var n int
+	for _, s := range kl.shards {
+		s.mu.Lock()
+		n += s.lru.Len()
+		s.mu.Unlock()
+	}
+	return n
+}
+
+func (kl *KeyedLimiter) get(key string, now time.Time) *Limiter {
+	s := kl.shard(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.evictExpired(now, kl.ttl)
+	if elem, ok := s.items[key]; ok {
+		entry := elem.Value.(*keyedEntry)
+		if now.After(entry.lastUsed) {
+			entry.lastUsed = now
+		}
+		s.lru.MoveToFront(elem)
+		return entry.lim
+	}
+
+	if s.lru.Len() >= s.maxKeys {
+		s.remove(s.lru.Back())
+	}
+	entry := &keyedEntry{key: key, lim: kl.newLimiter(key), lastUsed: now}
+	s.items[key] = s.lru.PushFront(entry)
+	return entry.lim
+}
+
+func (kl *KeyedLimiter) newLimiter(key string) *Limiter {
+	kl.omu.RLock()
+	l, ok := kl.overrides[key]
+	kl.omu.RUnlock()
+	if ok {
+		return NewLimiter(l.limit, l.burst, WithClock(kl.clock))
+	}
+	return NewLimiter(kl.limit, kl.burst, WithClock(kl.clock))
+}
+
+func (kl *KeyedLimiter) shard(key string) *keyedShard {
+	if len(kl.shards) == 1 {
+		return kl.shards[0]
+	}
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	return kl.shards[h.Sum32()%uint32(len(kl.shards))]
+}
+
+// evictExpired 从最久未使用的一端淘汰空闲超过 ttl 的 key
+func (s *keyedShard) evictExpired(now time.Time, ttl time.Duration) {
+	if ttl <= 0 {
+		return
+	}
+	for elem := s.lru.Back(); elem != nil; elem = s.lru.Back() {
+		if now.Sub(elem.Value.(*keyedEntry).lastUsed) < ttl {
+			return
+		}
+		s.remove(elem)
+	}
+}
+
+func (s *keyedShard) remove(elem *list.Element) {
+	s.lru.Remove(elem)
+	delete(s.items, elem.Value.(*keyedEntry).key)
+}
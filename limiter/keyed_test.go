@@ -0,0 +1,86 @@
+package limiter
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestKeyedLimiter_PerKey(t *testing.T) {
+	lim := NewKeyedLimiter(10, 1)
+	runKeyed(t, lim, "a", []allow{{t0, 1, true}, {t0, 1, false}})
+	runKeyed(t, lim, "b", []allow{{t0, 1, true}, {t0, 1, false}})
+	runKeyed(t, lim, "a", []allow{{t1, 1, true}})
+	if n := lim.Len(); n != 2 {
+		t.Errorf("Len() = %d want 2", n)
+	}
+}
+
+func TestKeyedLimiter_Override(t *testing.T) {
+	lim := NewKeyedLimiter(10, 1, WithKeyLimit("vip", 10, 3))
+	runKeyed(t, lim, "vip", []allow{{t0, 3, true}, {t0, 1, false}})
+	runKeyed(t, lim, "user", []allow{{t0, 1, true}, {t0, 1, false}})
+
+	// 已存在的 key 立即生效
+	lim.SetKeyLimit("user", Inf, 0)
+	runKeyed(t, lim, "user", []allow{{t0, 5, true}})
+	// 新建的 key 使用设置的值
+	lim.SetKeyLimit("new", 10, 2)
+	runKeyed(t, lim, "new", []allow{{t0, 2, true}, {t0, 1, false}})
+}
+
+func TestKeyedLimiter_LRU(t *testing.T) {
+	lim := NewKeyedLimiter(10, 1, WithMaxKeys(2))
+	runKeyed(t, lim, "a", []allow{{t0, 1, true}})
+	runKeyed(t, lim, "b", []allow{{t0, 1, true}})
+	runKeyed(t, lim, "a", []allow{{t0, 1, false}})
+	// b 最久未使用, 被淘汰
+	runKeyed(t, lim, "c", []allow{{t0, 1, true}})
+	if n := lim.Len(); n != 2 {
+		t.Errorf("Len() = %d want 2", n)
+	}
+	runKeyed(t, lim, "a", []allow{{t0, 1, false}})
+	runKeyed(t, lim, "b", []allow{{t0, 1, true}})
+}
+
+func TestKeyedLimiter_TTL(t *testing.T) {
+	lim := NewKeyedLimiter(1, 1, WithKeyTTL(3*d))
+	runKeyed(t, lim, "a", []allow{{t0, 1, true}})
+	runKeyed(t, lim, "b", []allow{{t2, 1, true}})
+	runKeyed(t, lim, "b", []allow{{t4, 1, false}})
+	// a 空闲超过 ttl 被淘汰后重新创建, b 未超过 ttl 仍保留
+	runKeyed(t, lim, "a", []allow{{t4, 1, true}})
+	runKeyed(t, lim, "b", []allow{{t5, 1, false}})
+}
+
+func TestKeyedLimiter_Concurrent(t *testing.T) {
+	lim := NewKeyedLimiter(Inf, 1, WithMaxKeys(1000))
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				if !lim.Allow(fmt.Sprintf("key-%d", (i*1000+j)%1500)) {
+					t.Errorf("Allow with Inf limit should succeed")
+					return
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	if n := lim.Len(); n > 1000 {
+		t.Errorf("Len() = %d want <= 1000", n)
+	}
+}
+
+func runKeyed(t *testing.T, lim *KeyedLimiter, key string, allows []allow) {
+	t.Helper()
+	for i, allow := range allows {
+		ok := lim.AllowN(key, allow.t, allow.n)
+		if ok != allow.ok {
+			t.Errorf("step %d: lim.AllowN(%q, %v, %v) = %v want %v",
+				i, key, allow.t, allow.n, ok, allow.ok)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package limiter
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// LeakyBucket 漏桶, 事件按 limit 的速率匀速流出, 不允许突发
+// 桶中最多排队 capacity 个事件, Allow 只在桶为空时返回 true, Wait 和 Reserve 会在桶中排队
+type LeakyBucket struct {
+	mu       sync.Mutex
+	limit    Limit
+	capacity int
+	// last 桶中已排队的事件全部流出的时间
+	last  time.Time
+	clock Clock
+}
+
+// NewLeakyBucket 实例化一个漏桶, limit 小于等于 0 时不允许任何事件
+func NewLeakyBucket(r Limit, capacity int, opts ...OptFn) *LeakyBucket {
+	op := applyOptions(options{}, opts)
+	return &LeakyBucket{
+		limit:    r,
+		capacity: capacity,
+		clock:    op.clock,
+	}
+}
+
+func (lb *LeakyBucket) Allow() bool {
+	return lb.AllowN(lb.clock.Now(), 1)
+}
+
+func (lb *LeakyBucket) AllowN(now time.Time, n int) bool {
+	return lb.reserveN(now, n, 0).ok
+}
+
+func (lb *LeakyBucket) Reserve() *Reservation {
+	return lb.ReserveN(lb.clock.Now(), 1)
+}
+
+func (lb *LeakyBucket) ReserveN(now time.Time, n int) *Reservation {
+	r := lb.reserveN(now, n, InfDuration)
+	return &r
+}
+
+func (lb *LeakyBucket) Wait(ctx context.Context) error {
+	return lb.WaitN(ctx, 1)
+}
+
+func (lb *LeakyBucket) WaitN(ctx context.Context, n int) error {
+	lb.mu.Lock()
+	limit := lb.limit
+	capacity := lb.capacity
+	lb.mu.Unlock()
+
+	if n > capacity && limit != Inf {
+		return fmt.Errorf("rate: Wait(n=%d) exceeds bucket capacity %d", n, capacity)
+	}
+	return waitN(ctx, n, lb.clock, lb.reserveN)
+}
+
+func (lb *LeakyBucket) reserveN(now time.Time, n int, maxFutureReserve time.Duration) Reservation {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+
+	if lb.limit == Inf || n <= 0 {
+		return Reservation{ok: true, tokens: n, timeToAct: now, clock: lb.clock}
+	}
+	if lb.limit <= 0 || n > lb.capacity {
+		return Reservation{}
+	}
+
+	start := lb.last
+	if start.Before(now) {
+		start = now
+	}
+	// 排在前面的事件流出所需的时间
+	waitDuration := start.Sub(now)
+	if waitDuration+lb.limit.durationFromTokens(float64(n)) > lb.limit.durationFromTokens(float64(lb.capacity)) ||
+		waitDuration > maxFutureReserve {
+		return Reservation{}
+	}
+
+	lb.last = start.Add(lb.limit.durationFromTokens(float64(n)))
+	limit := lb.limit
+	return Reservation{
+		ok:        true,
+		tokens:    n,
+		timeToAct: start,
+		limit:     limit,
+		clock:     lb.clock,
+		cancel: func(now time.Time) {
+			lb.mu.Lock()
+			defer lb.mu.Unlock()
+			if !start.After(now) {
+				return
+			}
+			lb.last = lb.last.Add(-limit.durationFromTokens(float64(n)))
+			if lb.last.Before(now) {
+				lb.last = now
+			}
+		},
+	}
+}
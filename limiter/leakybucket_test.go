@@ -0,0 +1,47 @@
+package limiter
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLeakyBucket_Allow(t *testing.T) {
+	// 不允许突发, 桶为空时才能立即通过
+	run(t, NewLeakyBucket(10, 3), []allow{
+		{t0, 1, true},
+		{t0, 1, false},
+		{t1, 1, true},
+		{t1, 1, false},
+		{t2, 4, false}, // capacity is 3, so n=4 always fails
+		{t2, 2, true},
+		{t3, 1, false},
+		{t4, 1, true},
+		{t4, -10, true},
+	})
+}
+
+func TestLeakyBucket_Reserve(t *testing.T) {
+	lim := NewLeakyBucket(10, 3)
+	for i := 0; i < 3; i++ {
+		r := lim.ReserveN(t0, 1)
+		if !r.Ok() {
+			t.Fatalf("reservation %d should be ok", i)
+		}
+		if delay, want := r.DelayFrom(t0), time.Duration(i)*d; delay != want {
+			t.Errorf("reservation %d delay = %v want %v", i, delay, want)
+		}
+	}
+	// 桶已满
+	if r := lim.ReserveN(t0, 1); r.Ok() {
+		t.Errorf("reservation on full bucket should fail")
+	}
+	// 流出一个事件后可以继续排队
+	r := lim.ReserveN(t1, 1)
+	if !r.Ok() || r.DelayFrom(t1) != 2*d {
+		t.Errorf("reservation at t1 = %v, %v want true, %v", r.Ok(), r.DelayFrom(t1), 2*d)
+	}
+	r.CancelAt(t1)
+	if r := lim.ReserveN(t1, 1); !r.Ok() || r.DelayFrom(t1) != 2*d {
+		t.Errorf("reservation after cancel = %v, %v want true, %v", r.Ok(), r.DelayFrom(t1), 2*d)
+	}
+}
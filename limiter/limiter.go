@@ -0,0 +1,65 @@
+package limiter
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// RateLimiter 各种限速算法的公共接口, 调用方可以在不修改代码的情况下切换算法
+type RateLimiter interface {
+	Allow() bool
+	// AllowN 判断指定时间是否能够允许n个事件
+	AllowN(now time.Time, n int) bool
+	// Wait 阻塞直到允许一个事件发生, ctx 结束或无法在 ctx 的截止时间前满足时返回错误
+	Wait(ctx context.Context) error
+	// Reserve 预约一个事件, 调用方需要等待 Reservation.Delay 后才能执行, 不执行时应调用 Cancel
+	Reserve() *Reservation
+}
+
+var (
+	_ RateLimiter = (*Limiter)(nil)
+	_ RateLimiter = (*NodeTokenLimiter)(nil)
+	_ RateLimiter = (*SlidingWindowLog)(nil)
+	_ RateLimiter = (*SlidingWindowCounter)(nil)
+	_ RateLimiter = (*LeakyBucket)(nil)
+	_ RateLimiter = (*GCRA)(nil)
+	_ RateLimiter = (*MultiLimiter)(nil)
+)
+
+// reserveFunc 在 now 预约n个事件, 等待时间超过 maxFutureReserve 时预约失败
+type reserveFunc = func(now time.Time, n int, maxFutureReserve time.Duration) Reservation
+
+// waitN 按 ctx 的截止时间预约n个事件并等待, ctx 提前结束时取消预约
+func waitN(ctx context.Context, n int, clock Clock, reserve reserveFunc) error {
+	// Check if ctx is already cancelled
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	now := clock.Now()
+	waitLimit := InfDuration // 等待时间的阈值
+	if deadline, ok := ctx.Deadline(); ok {
+		waitLimit = deadline.Sub(now) // 如果设置了超时时间，则获取超时时间作为等待时间的阈值
+	}
+
+	r := reserve(now, n, waitLimit)
+	if !r.Ok() {
+		return fmt.Errorf("rate: Wait(n=%d) would exceed context deadline", n)
+	}
+	delay := r.DelayFrom(now)
+	if delay == 0 {
+		return nil
+	}
+	t := clock.NewTimer(delay)
+	defer t.Stop()
+	select {
+	case <-t.C():
+		return nil
+	case <-ctx.Done():
+		r.CancelAt(clock.Now())
+		return ctx.Err()
+	}
+}
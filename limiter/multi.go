@@ -0,0 +1,126 @@
+package limiter
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+type (
+	// Tier MultiLimiter 中的一级限速, 例如每秒 10 次或每天 50000 次
+	Tier struct {
+		Name    string
+		Limiter *Limiter
+	}
+
+	// MultiLimiter 同时满足多级限速, 只有每一级都允许时才允许事件
+	// 某一级拒绝时, 已经在其他级预约的令牌通过 Reservation.CancelAt 归还
+	// 各级的 Limiter 不应再单独使用, 否则多级之间的预约不再是原子的
+	MultiLimiter struct {
+		mu    sync.Mutex
+		tiers []Tier
+		clock Clock
+	}
+)
+
+// NewMultiLimiter 实例化一个多级限速器, 按 tiers 的顺序依次预约
+func NewMultiLimiter(tiers []Tier, opts ...OptFn) *MultiLimiter {
+	op := applyOptions(options{}, opts)
+	return &MultiLimiter{
+		tiers: tiers,
+		clock: op.clock,
+	}
+}
+
+func (m *MultiLimiter) Allow() bool {
+	return m.AllowN(m.clock.Now(), 1)
+}
+
+func (m *MultiLimiter) AllowN(now time.Time, n int) bool {
+	ok, _ := m.AllowTier(now, n)
+	return ok
+}
+
+// AllowTier 与 AllowN 相同, 不允许时同时返回拒绝的那一级的名称
+func (m *MultiLimiter) AllowTier(now time.Time, n int) (bool, string) {
+	r, blocking := m.reserveN(now, n, 0)
+	if !r.ok {
+		return false, m.tiers[blocking].Name
+	}
+	return true, ""
+}
+
+func (m *MultiLimiter) Reserve() *Reservation {
+	return m.ReserveN(m.clock.Now(), 1)
+}
+
+func (m *MultiLimiter) ReserveN(now time.Time, n int) *Reservation {
+	r, _ := m.ReserveTier(now, n)
+	return r
+}
+
+// ReserveTier 与 ReserveN 相同, 同时返回限制这次预约的那一级的名称
+// 预约失败时为拒绝的那一级, 需要等待时为等待时间最长的那一级, 可以立即执行时为空
+func (m *MultiLimiter) ReserveTier(now time.Time, n int) (*Reservation, string) {
+	r, blocking := m.reserveN(now, n, InfDuration)
+	if blocking < 0 {
+		return &r, ""
+	}
+	return &r, m.tiers[blocking].Name
+}
+
+func (m *MultiLimiter) Wait(ctx context.Context) error {
+	return m.WaitN(ctx, 1)
+}
+
+func (m *MultiLimiter) WaitN(ctx context.Context, n int) error {
+	for _, tier := range m.tiers {
+		if limit, burst := tier.Limiter.Limit(), tier.Limiter.Burst(); n > burst && limit != Inf {
+			return fmt.Errorf("rate: Wait(n=%d) exceeds tier %q burst %d", n, tier.Name, burst)
+		}
+	}
+	return waitN(ctx, n, m.clock, func(now time.Time, n int, maxFutureReserve time.Duration) Reservation {
+		r, _ := m.reserveN(now, n, maxFutureReserve)
+		return r
+	})
+}
+
+// reserveN 依次在每一级预约, blocking 为拒绝或等待时间最长的那一级的下标, 没有时为 -1
+func (m *MultiLimiter) reserveN(now time.Time, n int, maxFutureReserve time.Duration) (Reservation, int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	blocking := -1
+	timeToAct := now
+	reserved := make([]Reservation, 0, len(m.tiers))
+	for i, tier := range m.tiers {
+		r := tier.Limiter.reserveN(now, n, maxFutureReserve)
+		if !r.ok {
+			// 回滚已经预约的各级, 后预约的先归还
+			for j := len(reserved) - 1; j >= 0; j-- {
+				reserved[j].CancelAt(now)
+			}
+			return Reservation{}, i
+		}
+		reserved = append(reserved, r)
+		if r.timeToAct.After(timeToAct) {
+			timeToAct = r.timeToAct
+			blocking = i
+		}
+	}
+
+	return Reservation{
+		ok:        true,
+		tokens:    n,
+		timeToAct: timeToAct,
+		clock:     m.clock,
+		cancel: func(now time.Time) {
+			m.mu.Lock()
+			defer m.mu.Unlock()
+			for j := len(reserved) - 1; j >= 0; j-- {
+				reserved[j].CancelAt(now)
+			}
+		},
+	}, blocking
+}
@@ -0,0 +1,98 @@
+package limiter
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// newTestMultiLimiter 每 100ms 一个令牌最多突发 2 个, 每 1s 一个令牌最多突发 3 个
+func newTestMultiLimiter(opts ...OptFn) *MultiLimiter {
+	return NewMultiLimiter([]Tier{
+		{Name: "fast", Limiter: NewLimiter(Every(d), 2)},
+		{Name: "slow", Limiter: NewLimiter(Every(10*d), 3)},
+	}, opts...)
+}
+
+func TestMultiLimiter_AllowTier(t *testing.T) {
+	m := newTestMultiLimiter()
+	cases := []struct {
+		t    time.Time
+		n    int
+		ok   bool
+		tier string
+	}{
+		{t0, 2, true, ""},
+		{t0, 1, false, "fast"},
+		{t1, 1, true, ""},
+		{t2, 1, false, "slow"},
+		{t5, 3, false, "fast"},
+		{t0.Add(10 * d), 1, true, ""},
+	}
+	for i, c := range cases {
+		ok, tier := m.AllowTier(c.t, c.n)
+		if ok != c.ok || tier != c.tier {
+			t.Errorf("step %d: AllowTier(%v, %d) = %v, %q want %v, %q", i, c.t, c.n, ok, tier, c.ok, c.tier)
+		}
+	}
+}
+
+func TestMultiLimiter_Rollback(t *testing.T) {
+	m := newTestMultiLimiter()
+	fast := m.tiers[0].Limiter
+	run(t, m, []allow{{t0, 2, true}, {t2, 1, true}})
+
+	// fast 已经恢复, slow 拒绝后 fast 预约的令牌应当归还
+	if ok, tier := m.AllowTier(t4, 2); ok || tier != "slow" {
+		t.Fatalf("AllowTier = %v, %q want false, %q", ok, tier, "slow")
+	}
+	run(t, fast, []allow{{t4, 2, true}})
+}
+
+func TestMultiLimiter_ReserveTier(t *testing.T) {
+	m := newTestMultiLimiter()
+	run(t, m, []allow{{t0, 2, true}})
+
+	r, tier := m.ReserveTier(t0, 1)
+	if !r.Ok() || tier != "fast" {
+		t.Fatalf("ReserveTier = %v, %q want true, %q", r.Ok(), tier, "fast")
+	}
+	if delay := r.DelayFrom(t0); delay != d {
+		t.Errorf("DelayFrom = %v want %v", delay, d)
+	}
+
+	r, tier = m.ReserveTier(t0, 1)
+	if !r.Ok() || tier != "slow" {
+		t.Fatalf("ReserveTier = %v, %q want true, %q", r.Ok(), tier, "slow")
+	}
+	if delay := r.DelayFrom(t0); delay != 10*d {
+		t.Errorf("DelayFrom = %v want %v", delay, 10*d)
+	}
+
+	// 取消后各级都归还令牌
+	r.CancelAt(t0)
+	if r, _ := m.ReserveTier(t0, 1); r.DelayFrom(t0) != 10*d {
+		t.Errorf("DelayFrom after cancel = %v want %v", r.DelayFrom(t0), 10*d)
+	}
+
+	if r, tier := m.ReserveTier(t0, 3); r.Ok() || tier != "fast" {
+		t.Errorf("ReserveTier(3) = %v, %q want false, %q", r.Ok(), tier, "fast")
+	}
+}
+
+func TestMultiLimiter_Wait(t *testing.T) {
+	clock := NewFakeClock(t0)
+	m := newTestMultiLimiter(WithClock(clock))
+	ctx := context.Background()
+	if err := m.WaitN(ctx, 3); err == nil {
+		t.Errorf("WaitN(3) exceeding fast burst should fail")
+	}
+	if err := m.WaitN(ctx, 2); err != nil {
+		t.Fatalf("WaitN(2): %v", err)
+	}
+	if err := waitAfter(t, clock, d, func() error {
+		return m.Wait(ctx)
+	}); err != nil {
+		t.Errorf("Wait: %v", err)
+	}
+}
@@ -0,0 +1,293 @@
+package limiter
+
+import "time"
+
+type (
+	// options 所有限速器共用的配置
+	options struct {
+		clock Clock
+	}
+
+	// OptFn 所有限速器共用的配置, 目前只有 WithClock, 可以传给任意使用时钟的构造函数
+	OptFn func(option *options)
+
+	// RedisOption NewRedisLimiter 的配置, WithClock 或 WithFallback
+	RedisOption interface {
+		applyRedis(option *redisOptions)
+	}
+
+	// RedisOptFn 只用于 NewRedisLimiter 的配置
+	RedisOptFn func(option *redisOptions)
+
+	redisOptions struct {
+		options
+		fallback *Limiter
+	}
+
+	// KeyedOption NewKeyedLimiter 的配置, WithClock 或 WithMaxKeys 等
+	KeyedOption interface {
+		applyKeyed(option *keyedOptions)
+	}
+
+	// KeyedOptFn 只用于 NewKeyedLimiter 的配置
+	KeyedOptFn func(option *keyedOptions)
+
+	keyedOptions struct {
+		options
+		maxKeys   int
+		keyTTL    time.Duration
+		keyLimits map[string]keyLimit
+	}
+
+	// ConcurrencyOption NewConcurrencyLimiter 和 NewAdaptiveLimiter 的配置, WithClock 或 WithMaxQueue 等
+	ConcurrencyOption interface {
+		applyConcurrency(option *concurrencyOptions)
+	}
+
+	// ConcurrencyOptFn 只用于 NewConcurrencyLimiter 和 NewAdaptiveLimiter 的配置
+	ConcurrencyOptFn func(option *concurrencyOptions)
+
+	concurrencyOptions struct {
+		options
+		maxQueue     int
+		queueTimeout time.Duration
+	}
+
+	// BreakerOption NewBreaker 的配置, WithClock 或 WithFailureRatio 等
+	BreakerOption interface {
+		applyBreaker(option *breakerOptions)
+	}
+
+	// BreakerOptFn 只用于 NewBreaker 的配置
+	BreakerOptFn func(option *breakerOptions)
+
+	breakerOptions struct {
+		options
+		failureRatio     float64
+		minRequests      int
+		maxConsecutive   int
+		breakerWindow    time.Duration
+		breakerBuckets   int
+		openTimeout      time.Duration
+		halfOpenRequests int
+		isFailure        func(err error) bool
+		onStateChange    func(from, to State)
+	}
+
+	// AIMDOptFn NewAIMD 的配置
+	AIMDOptFn func(option *aimdOptions)
+
+	aimdOptions struct {
+		backoffRatio float64
+		rttTimeout   time.Duration
+	}
+
+	// GradientOptFn NewGradient 的配置
+	GradientOptFn func(option *gradientOptions)
+
+	gradientOptions struct {
+		tolerance float64
+		smoothing float64
+	}
+
+	// MiddlewareOptFn HTTP 中间件和 gRPC 拦截器的配置
+	MiddlewareOptFn func(option *middlewareOptions)
+
+	middlewareOptions struct {
+		concurrency *ConcurrencyLimiter
+	}
+)
+
+// 共用的 OptFn 可以用于所有使用时钟的构造函数, 其他配置只能用于对应的构造函数
+var (
+	_ RedisOption       = OptFn(nil)
+	_ KeyedOption       = OptFn(nil)
+	_ ConcurrencyOption = OptFn(nil)
+	_ BreakerOption     = OptFn(nil)
+)
+
+// applyOptions 在默认配置 op 上应用 opts, 未指定时钟时使用系统时钟
+func applyOptions(op options, opts []OptFn) options {
+	for _, fn := range opts {
+		fn(&op)
+	}
+	op.setDefaults()
+	return op
+}
+
+// setDefaults 未指定时钟时使用系统时钟
+func (op *options) setDefaults() {
+	if op.clock == nil {
+		op.clock = defaultClock
+	}
+}
+
+func (fn OptFn) applyRedis(option *redisOptions) {
+	fn(&option.options)
+}
+
+func (fn OptFn) applyKeyed(option *keyedOptions) {
+	fn(&option.options)
+}
+
+func (fn OptFn) applyConcurrency(option *concurrencyOptions) {
+	fn(&option.options)
+}
+
+func (fn OptFn) applyBreaker(option *breakerOptions) {
+	fn(&option.options)
+}
+
+func (fn RedisOptFn) applyRedis(option *redisOptions) {
+	fn(option)
+}
+
+func (fn KeyedOptFn) applyKeyed(option *keyedOptions) {
+	fn(option)
+}
+
+func (fn ConcurrencyOptFn) applyConcurrency(option *concurrencyOptions) {
+	fn(option)
+}
+
+func (fn BreakerOptFn) applyBreaker(option *breakerOptions) {
+	fn(option)
+}
+
+// WithClock 限速器使用的时钟, 默认为系统时钟, 测试时可以使用 FakeClock
+func WithClock(clock Clock) OptFn {
+	return func(option *options) {
+		option.clock = clock
+	}
+}
+
+// WithFallback Redis 不可用时使用的本地限速器, 默认使用与 RedisLimiter 相同 limit 和 burst 的 Limiter
+func WithFallback(lim *Limiter) RedisOptFn {
+	return func(option *redisOptions) {
+		option.fallback = lim
+	}
+}
+
+// WithMaxKeys KeyedLimiter 最多保存的 key 数量, 超过时淘汰最久未使用的 key
+func WithMaxKeys(n int) KeyedOptFn {
+	return func(option *keyedOptions) {
+		option.maxKeys = n
+	}
+}
+
+// WithKeyTTL KeyedLimiter 中的 key 空闲超过 ttl 后被淘汰
+func WithKeyTTL(ttl time.Duration) KeyedOptFn {
+	return func(option *keyedOptions) {
+		option.keyTTL = ttl
+	}
+}
+
+// WithKeyLimit 单独设置 key 的 limit 和 burst
+func WithKeyLimit(key string, r Limit, b int) KeyedOptFn {
+	return func(option *keyedOptions) {
+		if option.keyLimits == nil {
+			option.keyLimits = make(map[string]keyLimit)
+		}
+		option.keyLimits[key] = keyLimit{limit: r, burst: b}
+	}
+}
+
+// WithMaxQueue ConcurrencyLimiter 许可用完时最多排队等待的调用方数量
+func WithMaxQueue(n int) ConcurrencyOptFn {
+	return func(option *concurrencyOptions) {
+		option.maxQueue = n
+	}
+}
+
+// WithQueueTimeout ConcurrencyLimiter 排队等待的最长时间, 默认等到 ctx 结束
+func WithQueueTimeout(timeout time.Duration) ConcurrencyOptFn {
+	return func(option *concurrencyOptions) {
+		option.queueTimeout = timeout
+	}
+}
+
+// WithConcurrency 中间件和拦截器在通过限速后, 还需要从 cl 获取许可才能处理请求
+func WithConcurrency(cl *ConcurrencyLimiter) MiddlewareOptFn {
+	return func(option *middlewareOptions) {
+		option.concurrency = cl
+	}
+}
+
+// WithBackoffRatio AIMD 请求被丢弃时上限乘以的比例, 默认 0.9
+func WithBackoffRatio(ratio float64) AIMDOptFn {
+	return func(option *aimdOptions) {
+		option.backoffRatio = ratio
+	}
+}
+
+// WithRTTTimeout AIMD 请求耗时超过 timeout 时与请求被丢弃同样处理
+func WithRTTTimeout(timeout time.Duration) AIMDOptFn {
+	return func(option *aimdOptions) {
+		option.rttTimeout = timeout
+	}
+}
+
+// WithTolerance Gradient 短期延迟不超过长期延迟的 tolerance 倍时不降低上限, 默认 1.5
+func WithTolerance(tolerance float64) GradientOptFn {
+	return func(option *gradientOptions) {
+		option.tolerance = tolerance
+	}
+}
+
+// WithSmoothing Gradient 每次调整时新上限所占的权重, 默认 0.2
+func WithSmoothing(smoothing float64) GradientOptFn {
+	return func(option *gradientOptions) {
+		option.smoothing = smoothing
+	}
+}
+
+// WithFailureRatio Breaker 窗口内请求数不少于 minRequests 且失败比例达到 ratio 时熔断, ratio 为 0 时不按比例熔断
+func WithFailureRatio(ratio float64, minRequests int) BreakerOptFn {
+	return func(option *breakerOptions) {
+		option.failureRatio = ratio
+		option.minRequests = minRequests
+	}
+}
+
+// WithConsecutiveFailures Breaker 连续失败 n 次时熔断
+func WithConsecutiveFailures(n int) BreakerOptFn {
+	return func(option *breakerOptions) {
+		option.maxConsecutive = n
+	}
+}
+
+// WithBreakerWindow Breaker 统计失败比例的滚动窗口, 分为 buckets 个时间桶, 默认 10 秒 10 个桶
+func WithBreakerWindow(window time.Duration, buckets int) BreakerOptFn {
+	return func(option *breakerOptions) {
+		option.breakerWindow = window
+		option.breakerBuckets = buckets
+	}
+}
+
+// WithOpenTimeout Breaker 熔断后经过 timeout 进入半开状态, 默认 5 秒
+func WithOpenTimeout(timeout time.Duration) BreakerOptFn {
+	return func(option *breakerOptions) {
+		option.openTimeout = timeout
+	}
+}
+
+// WithHalfOpenRequests Breaker 半开状态下放行的请求数, 全部成功后恢复, 默认 1
+func WithHalfOpenRequests(n int) BreakerOptFn {
+	return func(option *breakerOptions) {
+		option.halfOpenRequests = n
+	}
+}
+
+// WithIsFailure Breaker 判断 Execute 返回的错误是否计为失败, 默认非 nil 的错误都计为失败
+func WithIsFailure(fn func(err error) bool) BreakerOptFn {
+	return func(option *breakerOptions) {
+		option.isFailure = fn
+	}
+}
+
+// WithStateChange Breaker 状态变化时回调, 在锁外同步调用
+func WithStateChange(fn func(from, to State)) BreakerOptFn {
+	return func(option *breakerOptions) {
+		option.onStateChange = fn
+	}
+}
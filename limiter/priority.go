@@ -0,0 +1,194 @@
+package limiter
+
+import (
+	"container/heap"
+	"context"
+	"fmt"
+	"time"
+)
+
+type (
+	// priorityWaiter WaitPriority 的排队者, 只有队首持有预约
+	priorityWaiter struct {
+		n     int
+		prio  int
+		seq   uint64
+		index int
+		// r 队首持有的预约, 被更高优先级的排队者抢占时取消
+		r       *Reservation
+		granted bool
+		// wake 预约或放行状态变化时通知排队者
+		wake chan struct{}
+	}
+
+	// priorityQueue prio 大的在前, 相同 prio 先到的在前
+	priorityQueue []*priorityWaiter
+)
+
+func (q priorityQueue) Len() int {
+	return len(q)
+}
+
+func (q priorityQueue) Less(i, j int) bool {
+	if q[i].prio != q[j].prio {
+		return q[i].prio > q[j].prio
+	}
+	return q[i].seq < q[j].seq
+}
+
+func (q priorityQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *priorityQueue) Push(x interface{}) {
+	w := x.(*priorityWaiter)
+	w.index = len(*q)
+	*q = append(*q, w)
+}
+
+func (q *priorityQueue) Pop() interface{} {
+	old := *q
+	w := old[len(old)-1]
+	old[len(old)-1] = nil
+	*q = old[:len(old)-1]
+	return w
+}
+
+// WaitPriority 与 WaitN 相同, 但 WaitPriority 的调用方按 prio 从大到小得到令牌, 相同 prio 先到先得
+// 只有队首的调用方持有预约, 更高优先级的调用方到来时队首的预约被取消, 令牌让给新的队首
+// Allow, WaitN 等调用不参与排队
+func (lim *Limiter) WaitPriority(ctx context.Context, n, prio int) error {
+	lim.mu.Lock()
+	limit := lim.limit
+	burst := lim.burst
+	lim.mu.Unlock()
+
+	if n > burst && limit != Inf {
+		return fmt.Errorf("rate: Wait(n=%d) exceeds limiter's burst %d", n, burst)
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	lim.pmu.Lock()
+	if lim.waiters.Len() == 0 {
+		if r := lim.reserveN(lim.now(), n, 0); r.ok {
+			lim.pmu.Unlock()
+			return nil
+		}
+	}
+	lim.seq++
+	w := &priorityWaiter{n: n, prio: prio, seq: lim.seq, wake: make(chan struct{}, 1)}
+	heap.Push(&lim.waiters, w)
+	lim.schedule()
+	lim.pmu.Unlock()
+
+	for {
+		lim.pmu.Lock()
+		granted, r := w.granted, w.r
+		lim.pmu.Unlock()
+		if granted {
+			return nil
+		}
+
+		var (
+			t    Timer
+			fire <-chan time.Time
+		)
+		if r != nil {
+			deadline, hasDeadline := ctx.Deadline()
+			if !r.ok || hasDeadline && r.timeToAct.After(deadline) {
+				if lim.leave(w) {
+					return nil
+				}
+				return fmt.Errorf("rate: Wait(n=%d) would exceed context deadline", n)
+			}
+			t = lim.timer(r.DelayFrom(lim.now()))
+			fire = t.C()
+		}
+
+		select {
+		case <-fire:
+			lim.pmu.Lock()
+			if lim.holder == w {
+				lim.grant(w)
+				lim.schedule()
+			}
+			lim.pmu.Unlock()
+		case <-w.wake:
+		case <-ctx.Done():
+			if t != nil {
+				t.Stop()
+			}
+			if lim.leave(w) {
+				return nil
+			}
+			return ctx.Err()
+		}
+		if t != nil {
+			t.Stop()
+		}
+	}
+}
+
+// schedule 保证只有队首持有预约, 调用时持有 pmu
+func (lim *Limiter) schedule() {
+	now := lim.now()
+	if h := lim.holder; h != nil && (lim.waiters.Len() == 0 || lim.waiters[0] != h) {
+		if h.r.DelayFrom(now) == 0 {
+			// 预约已经到期, 令牌已经用掉, 直接放行
+			lim.grant(h)
+		} else {
+			h.r.CancelAt(now)
+			h.r = nil
+			lim.holder = nil
+			notifyWaiter(h)
+		}
+	}
+	if lim.holder == nil && lim.waiters.Len() > 0 {
+		head := lim.waiters[0]
+		r := lim.reserveN(now, head.n, InfDuration)
+		head.r = &r
+		lim.holder = head
+		notifyWaiter(head)
+	}
+}
+
+// grant 放行 w 并移出队列, 调用时持有 pmu
+func (lim *Limiter) grant(w *priorityWaiter) {
+	heap.Remove(&lim.waiters, w.index)
+	w.granted = true
+	w.r = nil
+	if lim.holder == w {
+		lim.holder = nil
+	}
+	notifyWaiter(w)
+}
+
+// leave w 放弃排队, 归还持有的预约, w 已经被放行时返回 true
+func (lim *Limiter) leave(w *priorityWaiter) bool {
+	lim.pmu.Lock()
+	defer lim.pmu.Unlock()
+	if w.granted {
+		return true
+	}
+	heap.Remove(&lim.waiters, w.index)
+	if lim.holder == w {
+		w.r.CancelAt(lim.now())
+		w.r = nil
+		lim.holder = nil
+	}
+	lim.schedule()
+	return false
+}
+
+func notifyWaiter(w *priorityWaiter) {
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+}
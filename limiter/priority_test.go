@@ -0,0 +1,99 @@
+package limiter
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestWaitPriority_Order(t *testing.T) {
+	clock := NewFakeClock(t0)
+	lim := NewLimiter(Every(d), 1, WithClock(clock))
+	lim.AllowN(t0, 1)
+
+	done := make(chan string, 4)
+	waitPriority := func(name string, prio int) {
+		go func() {
+			if err := lim.WaitPriority(context.Background(), 1, prio); err != nil {
+				t.Errorf("%s: WaitPriority: %v", name, err)
+			}
+			done <- name
+		}()
+	}
+	waitPriority("low1", 0)
+	waitQueuedPriority(t, lim, 1)
+	waitPriority("low2", 0)
+	waitQueuedPriority(t, lim, 2)
+	waitPriority("high", 1)
+	waitQueuedPriority(t, lim, 3)
+
+	// 高优先级先得到令牌, 相同优先级先到先得
+	for _, want := range []string{"high", "low1", "low2"} {
+		clock.Advance(d)
+		if got := <-done; got != want {
+			t.Fatalf("granted %s want %s", got, want)
+		}
+	}
+}
+
+func TestWaitPriority_Immediate(t *testing.T) {
+	lim := NewLimiter(Every(d), 2)
+	ctx := context.Background()
+	if err := lim.WaitPriority(ctx, 2, 0); err != nil {
+		t.Errorf("WaitPriority: %v", err)
+	}
+	if err := lim.WaitPriority(ctx, 3, 0); err == nil {
+		t.Errorf("WaitPriority(3) with burst 2 should fail")
+	}
+}
+
+func TestWaitPriority_Cancel(t *testing.T) {
+	clock := NewFakeClock(t0)
+	lim := NewLimiter(Every(d), 1, WithClock(clock))
+	lim.AllowN(t0, 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- lim.WaitPriority(ctx, 1, 0)
+	}()
+	waitQueuedPriority(t, lim, 1)
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Fatalf("WaitPriority = %v want %v", err, context.Canceled)
+	}
+	// 取消的预约归还令牌
+	run(t, lim, []allow{{t1, 1, true}, {t1, 1, false}})
+	if n := lim.waiters.Len(); n != 0 {
+		t.Errorf("%d waiters left in queue", n)
+	}
+}
+
+func TestWaitPriority_Deadline(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	lim := NewLimiter(Every(d), 1, WithClock(clock))
+	lim.AllowN(clock.Now(), 1)
+
+	ctx, cancel := context.WithDeadline(context.Background(), clock.Now().Add(d/2))
+	defer cancel()
+	if err := lim.WaitPriority(ctx, 1, 0); err == nil {
+		t.Errorf("WaitPriority beyond deadline should fail")
+	}
+	clock.Advance(d)
+	run(t, lim, []allow{{clock.Now(), 1, true}})
+}
+
+// waitQueuedPriority 等待 WaitPriority 的排队者达到 n 个
+func waitQueuedPriority(t *testing.T, lim *Limiter, n int) {
+	t.Helper()
+	for i := 0; i < 1000; i++ {
+		lim.pmu.Lock()
+		queued := lim.waiters.Len()
+		lim.pmu.Unlock()
+		if queued >= n {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("waiters did not reach %d", n)
+}
@@ -0,0 +1,214 @@
+package limiter
+
+import (
+	"math"
+	"time"
+)
+
+// quotaReporter 能够报告当前额度的限速器, 中间件用来设置 X-RateLimit-* 响应头
+// limit 最多允许的突发事件数, remaining 当前还能立即允许的事件数, reset 额度完全恢复所需的时间
+type quotaReporter interface {
+	quota() (limit, remaining int, reset time.Duration)
+}
+
+// retryReporter 能够不改变状态地计算下一个事件需要等待的时间, 中间件用来设置 Retry-After 响应头
+// 永远无法满足时返回 InfDuration
+type retryReporter interface {
+	retryAfter() time.Duration
+}
+
+var (
+	_ retryReporter = (*Limiter)(nil)
+	_ retryReporter = (*NodeTokenLimiter)(nil)
+	_ retryReporter = (*GCRA)(nil)
+	_ retryReporter = (*LeakyBucket)(nil)
+	_ retryReporter = (*SlidingWindowLog)(nil)
+	_ retryReporter = (*SlidingWindowCounter)(nil)
+	_ retryReporter = (*MultiLimiter)(nil)
+)
+
+var (
+	_ quotaReporter = (*Limiter)(nil)
+	_ quotaReporter = (*GCRA)(nil)
+	_ quotaReporter = (*SlidingWindowLog)(nil)
+	_ quotaReporter = (*SlidingWindowCounter)(nil)
+	_ quotaReporter = (*MultiLimiter)(nil)
+)
+
+func (lim *Limiter) quota() (int, int, time.Duration) {
+	lim.mu.Lock()
+	defer lim.mu.Unlock()
+	now := lim.now()
+
+	_, _, tokens := lim.advance(now)
+	var reset time.Duration
+	if lim.limit > 0 && lim.limit != Inf && tokens < float64(lim.burst) {
+		reset = lim.limit.durationFromTokens(float64(lim.burst) - tokens)
+	}
+	return lim.burst, remaining(tokens), reset
+}
+
+func (g *GCRA) quota() (int, int, time.Duration) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	now := g.clock.Now()
+
+	if g.limit <= 0 || g.limit == Inf || !g.tat.After(now) {
+		return g.burst, g.burst, 0
+	}
+	reset := g.tat.Sub(now)
+	return g.burst, remaining(float64(g.burst) - g.limit.tokensFromDuration(reset)), reset
+}
+
+func (w *SlidingWindowLog) quota() (int, int, time.Duration) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	now := w.clock.Now()
+
+	inWindow := w.count
+	var reset time.Duration
+	for _, e := range w.events {
+		if end := e.at.Add(w.window); end.After(now) {
+			if reset == 0 {
+				reset = end.Sub(now)
+			}
+			continue
+		}
+		inWindow -= e.n
+	}
+	return w.limit, remaining(float64(w.limit - inWindow)), reset
+}
+
+func (w *SlidingWindowCounter) quota() (int, int, time.Duration) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	now := w.clock.Now()
+
+	if w.window <= 0 {
+		return w.limit, w.limit, 0
+	}
+	window := int64(w.window)
+	k := now.UnixNano() / window
+	elapsed := now.UnixNano() - k*window
+	estimated := float64(w.counts[k-1])*float64(window-elapsed)/float64(window) + float64(w.counts[k])
+	return w.limit, remaining(float64(w.limit) - estimated), time.Duration(window - elapsed)
+}
+
+// quota 剩余额度最少的那一级的额度
+func (m *MultiLimiter) quota() (int, int, time.Duration) {
+	var (
+		limit, left int
+		reset       time.Duration
+	)
+	for i, tier := range m.tiers {
+		l, r, d := tier.Limiter.quota()
+		if i == 0 || r < left || r == left && d > reset {
+			limit, left, reset = l, r, d
+		}
+	}
+	return limit, left, reset
+}
+
+func (lim *Limiter) retryAfter() time.Duration {
+	lim.mu.Lock()
+	defer lim.mu.Unlock()
+	if lim.limit == Inf {
+		return 0
+	}
+	if lim.burst < 1 {
+		return InfDuration
+	}
+	_, _, tokens := lim.advance(lim.now())
+	if tokens >= 1 {
+		return 0
+	}
+	return lim.limit.durationFromTokens(1 - tokens)
+}
+
+// retryAfter x/time/rate 没有只读的接口, 按补充一个令牌的时间估计
+func (nl *NodeTokenLimiter) retryAfter() time.Duration {
+	limit := Limit(nl.lim.Limit())
+	if limit == Inf {
+		return 0
+	}
+	if nl.lim.Burst() < 1 {
+		return InfDuration
+	}
+	return limit.durationFromTokens(1)
+}
+
+func (g *GCRA) retryAfter() time.Duration {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.limit == Inf {
+		return 0
+	}
+	if g.limit <= 0 || g.burst < 1 {
+		return InfDuration
+	}
+	now := g.clock.Now()
+	if _, allowAt := g.allowAt(now, 1); allowAt.After(now) {
+		return allowAt.Sub(now)
+	}
+	return 0
+}
+
+func (lb *LeakyBucket) retryAfter() time.Duration {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+	if lb.limit == Inf {
+		return 0
+	}
+	if lb.limit <= 0 || lb.capacity < 1 {
+		return InfDuration
+	}
+	// Allow 只在桶为空时允许
+	if now := lb.clock.Now(); lb.last.After(now) {
+		return lb.last.Sub(now)
+	}
+	return 0
+}
+
+func (w *SlidingWindowLog) retryAfter() time.Duration {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.window <= 0 {
+		return 0
+	}
+	if w.limit < 1 {
+		return InfDuration
+	}
+	now := w.clock.Now()
+	return w.earliest(now, 1).Sub(now)
+}
+
+func (w *SlidingWindowCounter) retryAfter() time.Duration {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.window <= 0 {
+		return 0
+	}
+	if w.limit < 1 {
+		return InfDuration
+	}
+	_, wait := w.earliest(w.clock.Now(), 1)
+	return wait
+}
+
+// retryAfter 等待时间最长的那一级的等待时间
+func (m *MultiLimiter) retryAfter() time.Duration {
+	var wait time.Duration
+	for _, tier := range m.tiers {
+		if d := tier.Limiter.retryAfter(); d > wait {
+			wait = d
+		}
+	}
+	return wait
+}
+
+func remaining(tokens float64) int {
+	if tokens <= 0 {
+		return 0
+	}
+	return int(math.Floor(tokens))
+}
@@ -10,7 +10,7 @@ import (
 
 // Limit 定义某些事件的最大频率。
 // Limit 表示为每秒的事件数。
-// zero Limit 不允许任何事件
+// zero Limit 不再补充令牌, 桶中剩余的令牌用完后不允许任何事件, 新建的 Limiter 桶是空的
 type Limit float64
 
 // Inf 无限速 Limit 允许任何事件（即使burst是0）
@@ -33,6 +33,14 @@ type Limiter struct {
 	// last 指的是 tokens 最近一次被更新的时间
 	last      time.Time
 	lastEvent time.Time
+	clock     Clock
+
+	// pmu 保护 WaitPriority 的排队状态, 先于 mu 加锁
+	pmu     sync.Mutex
+	waiters priorityQueue
+	// holder 持有预约的队首
+	holder *priorityWaiter
+	seq    uint64
 }
 
 // Limit 返回最大的总体速率限制
@@ -50,18 +58,36 @@ func (lim *Limiter) Burst() int {
 }
 
 // NewLimiter 实例化一个限制器
-func NewLimiter(r Limit, b int) *Limiter {
+func NewLimiter(r Limit, b int, opts ...OptFn) *Limiter {
+	op := applyOptions(options{}, opts)
 	return &Limiter{
 		limit: r,
 		burst: b,
+		clock: op.clock,
 	}
 }
 
+// clockOrDefault 零值 Limiter 没有 clock, 使用系统时钟
+func (lim *Limiter) clockOrDefault() Clock {
+	if lim.clock == nil {
+		return defaultClock
+	}
+	return lim.clock
+}
+
+func (lim *Limiter) now() time.Time {
+	return lim.clockOrDefault().Now()
+}
+
+func (lim *Limiter) timer(d time.Duration) Timer {
+	return lim.clockOrDefault().NewTimer(d)
+}
+
 func (lim *Limiter) Allow() bool {
-	return lim.AllowN(time.Now(), 1)
+	return lim.AllowN(lim.now(), 1)
 }
 
-// AllowN 判断指定时间是否能够允许N个时间
+// AllowN 判断指定时间是否能够允许N个事件, n 小于等于 0 时总是允许且不消耗令牌
 func (lim *Limiter) AllowN(now time.Time, n int) bool {
 	return lim.reserveN(now, n, 0).ok
 }
@@ -74,6 +100,10 @@ type Reservation struct {
 	tokens    int
 	timeToAct time.Time
 	limit     Limit
+	// cancel 其他 RateLimiter 实现的取消逻辑, lim 为 nil 时使用
+	cancel func(now time.Time)
+	// clock 其他 RateLimiter 实现的时钟, lim 为 nil 时使用
+	clock Clock
 }
 
 func (r *Reservation) Ok() bool {
@@ -81,7 +111,7 @@ func (r *Reservation) Ok() bool {
 }
 
 func (r *Reservation) Delay() time.Duration {
-	return r.DelayFrom(time.Now())
+	return r.DelayFrom(r.now())
 }
 
 const InfDuration = time.Duration(1<<63 - 1)
@@ -98,13 +128,29 @@ func (r *Reservation) DelayFrom(now time.Time) time.Duration {
 }
 
 func (r *Reservation) Cancel() {
-	r.CancelAt(time.Now())
+	r.CancelAt(r.now())
+}
+
+func (r *Reservation) now() time.Time {
+	if r.lim != nil {
+		return r.lim.now()
+	}
+	if r.clock != nil {
+		return r.clock.Now()
+	}
+	return defaultClock.Now()
 }
 
 func (r *Reservation) CancelAt(now time.Time) {
 	if !r.ok {
 		return
 	}
+	if r.lim == nil {
+		if r.cancel != nil {
+			r.cancel(now)
+		}
+		return
+	}
 	r.lim.mu.Lock()
 	defer r.lim.mu.Unlock()
 
@@ -128,7 +174,7 @@ func (r *Reservation) CancelAt(now time.Time) {
 	// 更新状态
 	r.lim.last = now
 	r.lim.tokens = tokens
-	if r.timeToAct == r.lim.lastEvent {
+	if r.timeToAct == r.lim.lastEvent && r.limit > 0 {
 		prevEvent := r.timeToAct.Add(r.limit.durationFromTokens(float64(-r.tokens)))
 		if !prevEvent.Before(now) {
 			r.lim.lastEvent = prevEvent
@@ -137,7 +183,7 @@ func (r *Reservation) CancelAt(now time.Time) {
 }
 
 func (lim *Limiter) Reserve() *Reservation {
-	return lim.ReserveN(time.Now(), 1)
+	return lim.ReserveN(lim.now(), 1)
 }
 
 func (lim *Limiter) ReserveN(now time.Time, n int) *Reservation {
@@ -159,40 +205,11 @@ func (lim *Limiter) WaitN(ctx context.Context, n int) error {
 	if n > burst && limit != Inf {
 		return fmt.Errorf("rate: Wait(n=%d) exceeds limiter's burst %d", n, burst)
 	}
-	// Check if ctx is already cancelled
-	select {
-	case <-ctx.Done():
-		return ctx.Err()
-	default:
-	}
-
-	now := time.Now()
-	waitLimit := InfDuration // 等待时间的阈值
-	if deadline, ok := ctx.Deadline(); ok {
-		waitLimit = deadline.Sub(now) // 如果设置了超时时间，则获取超时时间作为等待时间的阈值
-	}
-
-	r := lim.reserveN(now, n, waitLimit)
-	if !r.Ok() {
-		return fmt.Errorf("rate: Wait(n=%d) would exceed context deadline", n)
-	}
-	delay := r.DelayFrom(now)
-	if delay == 0 {
-		return nil
-	}
-	t := time.NewTimer(delay)
-	defer t.Stop()
-	select {
-	case <-t.C:
-		return nil
-	case <-ctx.Done():
-		r.Cancel()
-		return ctx.Err()
-	}
+	return waitN(ctx, n, lim.clockOrDefault(), lim.reserveN)
 }
 
 func (lim *Limiter) SetLimit(newLimit Limit) {
-	lim.SetLimitAt(time.Now(), newLimit)
+	lim.SetLimitAt(lim.now(), newLimit)
 }
 
 func (lim *Limiter) SetLimitAt(now time.Time, newLimit Limit) {
@@ -208,7 +225,7 @@ func (lim *Limiter) SetLimitAt(now time.Time, newLimit Limit) {
 }
 
 func (lim *Limiter) SetBurst(newBurst int) {
-	lim.SetBurstAt(time.Now(), newBurst)
+	lim.SetBurstAt(lim.now(), newBurst)
 }
 
 // SetBurstAt 重新设置一个新突发大小
@@ -229,6 +246,10 @@ func (lim *Limiter) reserveN(now time.Time, n int, maxFutureReserve time.Duratio
 	lim.mu.Lock()
 	defer lim.mu.Unlock()
 
+	if n < 0 {
+		// n 小于 0 时直接允许, 不能反向增加令牌
+		n = 0
+	}
 	if lim.limit == Inf {
 		return Reservation{
 			ok:        true,
@@ -236,19 +257,6 @@ func (lim *Limiter) reserveN(now time.Time, n int, maxFutureReserve time.Duratio
 			tokens:    n,
 			timeToAct: now,
 		}
-	} else if lim.limit == 0 {
-		// zero Limiter
-		var ok bool
-		if lim.burst > n {
-			ok = true
-			lim.burst -= n
-		}
-		return Reservation{
-			ok:        ok,
-			lim:       lim,
-			tokens:    lim.burst,
-			timeToAct: now,
-		}
 	}
 	now, last, tokens := lim.advance(now)
 	// 计算请求产生的剩余令牌数量。
@@ -258,7 +266,8 @@ func (lim *Limiter) reserveN(now time.Time, n int, maxFutureReserve time.Duratio
 	if tokens < 0 {
 		waitDuration = lim.limit.durationFromTokens(-tokens)
 	}
-	ok := n <= lim.burst && waitDuration <= maxFutureReserve
+	// limit 小于等于 0 时不再补充令牌, 令牌不足的请求永远不会被允许
+	ok := n <= lim.burst && waitDuration != InfDuration && waitDuration <= maxFutureReserve
 	r := Reservation{
 		ok:    ok,
 		lim:   lim,
@@ -294,7 +303,7 @@ func (lim *Limiter) advance(now time.Time) (newNow, newLast time.Time, newTokens
 
 // 累积到tokens数量的令牌需要的时间
 func (limit Limit) durationFromTokens(tokens float64) time.Duration {
-	if limit < 0 {
+	if limit <= 0 {
 		return InfDuration
 	}
 	seconds := tokens / float64(limit)
@@ -0,0 +1,120 @@
+package limiter
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// 以 golang.org/x/time/rate 为参照, 随机的操作序列下 Limiter 的行为应当一致
+// 参照实现在 zero Limit 和 n 小于 0 时行为有误, 这两种情况单独测试
+
+// 参照实现计算令牌时把时间截断到纳秒, 为了精确比较, limit 取 2 的幂, 时间间隔取 propertyTick 的整数倍
+// 这样令牌数和时间都能用浮点数精确表示
+var propertyLimits = []Limit{0.5, 1, 2, 8, 64, Inf}
+
+const propertyTick = time.Second / 512
+
+func TestLimiterMatchesReference(t *testing.T) {
+	for seed := int64(0); seed < 200; seed++ {
+		rnd := rand.New(rand.NewSource(seed))
+		limit := propertyLimits[rnd.Intn(len(propertyLimits))]
+		burst := rnd.Intn(5) + 1
+		lim := NewLimiter(limit, burst)
+		ref := rate.NewLimiter(rate.Limit(limit), burst)
+
+		now := t0
+		var pending []reservationPair
+		for step := 0; step < 100; step++ {
+			now = now.Add(time.Duration(rnd.Intn(100)) * propertyTick)
+			n := rnd.Intn(burst + 2)
+			switch op := rnd.Intn(10); {
+			case op < 5:
+				got, want := lim.AllowN(now, n), ref.AllowN(now, n)
+				if got != want {
+					t.Fatalf("seed %d step %d: AllowN(%d) = %v want %v", seed, step, n, got, want)
+				}
+			case op < 8:
+				got, want := lim.ReserveN(now, n), ref.ReserveN(now, n)
+				if got.Ok() != want.OK() {
+					t.Fatalf("seed %d step %d: ReserveN(%d).Ok() = %v want %v", seed, step, n, got.Ok(), want.OK())
+				}
+				gotDelay, wantDelay := got.DelayFrom(now), want.DelayFrom(now)
+				if gotDelay != wantDelay {
+					t.Fatalf("seed %d step %d: ReserveN(%d).Delay() = %v want %v", seed, step, n, gotDelay, wantDelay)
+				}
+				pending = append(pending, reservationPair{got, want})
+			case len(pending) > 0:
+				i := rnd.Intn(len(pending))
+				pending[i].got.CancelAt(now)
+				pending[i].want.CancelAt(now)
+				pending = append(pending[:i], pending[i+1:]...)
+			}
+		}
+	}
+}
+
+type reservationPair struct {
+	got  *Reservation
+	want *rate.Reservation
+}
+
+func TestLimiterZeroLimitProperty(t *testing.T) {
+	for seed := int64(0); seed < 100; seed++ {
+		rnd := rand.New(rand.NewSource(seed))
+		burst := rnd.Intn(5) + 1
+		lim := NewLimiter(10, burst)
+		now := t0
+		left := burst - rnd.Intn(burst+1)
+		lim.AllowN(now, burst-left)
+		lim.SetLimitAt(now, 0)
+
+		// 不再补充令牌, 只能用完剩余的 left 个令牌, burst 保持不变
+		for step := 0; step < 50; step++ {
+			now = now.Add(time.Duration(rnd.Intn(1000)) * time.Millisecond)
+			n := rnd.Intn(burst) + 1
+			want := n <= left
+			if got := lim.AllowN(now, n); got != want {
+				t.Fatalf("seed %d step %d: AllowN(%d) = %v want %v", seed, step, n, got, want)
+			}
+			if want {
+				left -= n
+			}
+			if r := lim.ReserveN(now, left+1); r.Ok() {
+				t.Fatalf("seed %d step %d: ReserveN(%d) with %d tokens left should fail", seed, step, left+1, left)
+			}
+		}
+		if got := lim.Burst(); got != burst {
+			t.Fatalf("seed %d: Burst() = %d want %d", seed, got, burst)
+		}
+	}
+}
+
+func TestLimiterNegativeNProperty(t *testing.T) {
+	for seed := int64(0); seed < 100; seed++ {
+		rnd := rand.New(rand.NewSource(seed))
+		limit := propertyLimits[rnd.Intn(len(propertyLimits))]
+		burst := rnd.Intn(5) + 1
+		lim := NewLimiter(limit, burst)
+		ref := rate.NewLimiter(rate.Limit(limit), burst)
+
+		// n 小于 0 的请求总是被允许且不改变状态, 忽略这些请求后应与参照一致
+		now := t0
+		for step := 0; step < 100; step++ {
+			now = now.Add(time.Duration(rnd.Intn(100)) * propertyTick)
+			n := rnd.Intn(burst+4) - 3
+			got := lim.AllowN(now, n)
+			if n < 0 {
+				if !got {
+					t.Fatalf("seed %d step %d: AllowN(%d) = false want true", seed, step, n)
+				}
+				continue
+			}
+			if want := ref.AllowN(now, n); got != want {
+				t.Fatalf("seed %d step %d: AllowN(%d) = %v want %v", seed, step, n, got, want)
+			}
+		}
+	}
+}
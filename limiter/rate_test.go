@@ -1,6 +1,7 @@
 package limiter
 
 import (
+	"context"
 	"fmt"
 	"github.com/stretchr/testify/assert"
 	"math"
@@ -66,7 +67,7 @@ type allow struct {
 	ok bool
 }
 
-func run(t *testing.T, lim *Limiter, allows []allow) {
+func run(t *testing.T, lim RateLimiter, allows []allow) {
 	t.Helper()
 	for i, allow := range allows {
 		ok := lim.AllowN(allow.t, allow.n)
@@ -109,3 +110,146 @@ func TestLimiterBurst3(t *testing.T) {
 		{t9, 0, true},
 	})
 }
+
+func TestLimiterZeroValue(t *testing.T) {
+	var lim Limiter
+	if lim.Allow() {
+		t.Errorf("zero value Limiter should reject all events")
+	}
+	if r := lim.Reserve(); r.Ok() {
+		t.Errorf("Reserve on zero value Limiter should fail")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), d)
+	defer cancel()
+	if err := lim.Wait(ctx); err == nil {
+		t.Errorf("Wait on zero value Limiter should fail")
+	}
+	if err := lim.WaitPriority(ctx, 1, 0); err == nil {
+		t.Errorf("WaitPriority on zero value Limiter should fail")
+	}
+
+	lim.SetLimit(Inf)
+	if !lim.Allow() {
+		t.Errorf("Allow with Inf limit = false want true")
+	}
+	r := lim.Reserve()
+	if !r.Ok() || r.Delay() != 0 {
+		t.Errorf("Reserve with Inf limit = %v, %v want ok without delay", r.Ok(), r.Delay())
+	}
+	r.Cancel()
+	if err := lim.WaitPriority(ctx, 1, 0); err != nil {
+		t.Errorf("WaitPriority with Inf limit: %v", err)
+	}
+}
+
+func TestLimiterZeroLimit(t *testing.T) {
+	lim := NewLimiter(0, 3)
+	run(t, lim, []allow{
+		{t0, 1, false},
+		{t9, 1, false},
+		{t9, 0, true},
+	})
+	if r := lim.ReserveN(t9, 1); r.Ok() {
+		t.Errorf("ReserveN on zero Limit should fail, got delay %v", r.DelayFrom(t9))
+	}
+	if burst := lim.Burst(); burst != 3 {
+		t.Errorf("Burst() = %d want 3", burst)
+	}
+
+	// 调整为 0 后只能用完桶中剩余的令牌
+	lim = NewLimiter(10, 3)
+	lim.AllowN(t0, 1)
+	lim.SetLimitAt(t0, 0)
+	run(t, lim, []allow{
+		{t0, 3, false},
+		{t0, 2, true},
+		{t9, 1, false},
+	})
+	if burst := lim.Burst(); burst != 3 {
+		t.Errorf("Burst() = %d want 3", burst)
+	}
+}
+
+func TestLimiterNegativeN(t *testing.T) {
+	lim := NewLimiter(10, 2)
+	run(t, lim, []allow{
+		{t0, -5, true},
+		{t0, 2, true},
+		{t0, -5, true},
+		{t0, 1, false},
+	})
+	r := lim.ReserveN(t0, -1)
+	if !r.Ok() || r.DelayFrom(t0) != 0 {
+		t.Errorf("ReserveN(-1) = %v, %v want ok without delay", r.Ok(), r.DelayFrom(t0))
+	}
+	r.CancelAt(t0)
+	run(t, lim, []allow{{t0, 1, false}})
+}
+
+func TestWaitN(t *testing.T) {
+	clock := NewFakeClock(t0)
+	lim := NewLimiter(10, 1, WithClock(clock))
+	ctx := context.Background()
+	if err := lim.Wait(ctx); err != nil {
+		t.Fatalf("first Wait: %v", err)
+	}
+	if err := waitAfter(t, clock, d, func() error {
+		return lim.Wait(ctx)
+	}); err != nil {
+		t.Errorf("second Wait: %v", err)
+	}
+	if err := lim.WaitN(ctx, 2); err == nil {
+		t.Errorf("WaitN(2) with burst 1 should fail")
+	}
+}
+
+func TestWaitNDeadline(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	lim := NewLimiter(10, 1, WithClock(clock))
+	lim.AllowN(clock.Now(), 1)
+	ctx, cancel := context.WithDeadline(context.Background(), clock.Now().Add(d/2))
+	defer cancel()
+	if err := lim.Wait(ctx); err == nil {
+		t.Errorf("Wait beyond deadline should fail")
+	}
+	// 失败的 Wait 不消耗令牌
+	clock.Advance(d)
+	run(t, lim, []allow{{clock.Now(), 1, true}})
+}
+
+func TestWaitNCancel(t *testing.T) {
+	clock := NewFakeClock(t0)
+	lim := NewLimiter(10, 1, WithClock(clock))
+	lim.AllowN(t0, 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- lim.Wait(ctx)
+	}()
+	clock.WaitForTimers(1)
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Errorf("Wait = %v want %v", err, context.Canceled)
+	}
+	// 取消的预约归还令牌
+	run(t, lim, []allow{{t1, 1, true}, {t1, 1, false}})
+}
+
+// waitAfter 在 wait 开始等待后将 clock 前进 d, 返回 wait 的结果
+func waitAfter(t *testing.T, clock *FakeClock, d time.Duration, wait func() error) error {
+	t.Helper()
+	done := make(chan error, 1)
+	go func() {
+		done <- wait()
+	}()
+	for clock.Timers() == 0 {
+		select {
+		case err := <-done:
+			t.Fatalf("returned %v without waiting", err)
+		case <-time.After(time.Millisecond):
+		}
+	}
+	clock.Advance(d)
+	return <-done
+}
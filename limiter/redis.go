@@ -0,0 +1,184 @@
+package limiter
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+// tokenBucketScript 在 Redis 中原子地完成令牌桶的补充和扣减
+// KEYS[1] 令牌桶的 key
+// ARGV 依次为 每秒令牌数, 桶容量, 当前时间(微秒), 请求的令牌数, key 的过期时间(毫秒, 0 表示不过期)
+// 返回 {是否允许, 还需等待的微秒数(-1 表示永远无法满足)}
+var tokenBucketScript = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local n = tonumber(ARGV[4])
+local ttl = tonumber(ARGV[5])
+
+local state = redis.call("HMGET", KEYS[1], "tokens", "last")
+local tokens = tonumber(state[1])
+local last = tonumber(state[2])
+if tokens == nil or last == nil then
+	-- 与本地 Limiter 一致, limit 为 0 时桶从空开始
+	tokens = 0
+	if rate > 0 then
+		tokens = burst
+	end
+	last = now
+end
+-- 各节点的时钟可能不一致, 时间不回退
+if now < last then
+	now = last
+end
+if rate > 0 then
+	tokens = math.min(burst, tokens + (now - last) * rate / 1000000)
+end
+
+if tokens >= n then
+	redis.call("HMSET", KEYS[1], "tokens", tokens - n, "last", now)
+	if ttl > 0 then
+		redis.call("PEXPIRE", KEYS[1], ttl)
+	end
+	return {1, 0}
+end
+if rate <= 0 then
+	return {0, -1}
+end
+return {0, math.ceil((n - tokens) * 1000000 / rate)}
+`)
+
+// RedisLimiter 令牌桶保存在 Redis 中的限速器, 使用同一个 key 的多个进程共享 limit
+// Redis 不可用时退化为本地的 Limiter
+type RedisLimiter struct {
+	client   redis.Cmdable
+	key      string
+	limit    Limit
+	burst    int
+	fallback *Limiter
+	clock    Clock
+}
+
+// NewRedisLimiter 实例化一个 Redis 限速器, 相同 key 的限速器共享同一个令牌桶
+func NewRedisLimiter(client redis.Cmdable, key string, r Limit, b int, opts ...RedisOption) *RedisLimiter {
+	var op redisOptions
+	for _, opt := range opts {
+		opt.applyRedis(&op)
+	}
+	op.setDefaults()
+	if op.fallback == nil {
+		op.fallback = NewLimiter(r, b, WithClock(op.clock))
+	}
+	return &RedisLimiter{
+		client:   client,
+		key:      key,
+		limit:    r,
+		burst:    b,
+		fallback: op.fallback,
+		clock:    op.clock,
+	}
+}
+
+func (rl *RedisLimiter) Limit() Limit {
+	return rl.limit
+}
+
+func (rl *RedisLimiter) Burst() int {
+	return rl.burst
+}
+
+func (rl *RedisLimiter) Allow() bool {
+	return rl.AllowN(rl.clock.Now(), 1)
+}
+
+// AllowN 判断指定时间是否能够允许n个事件, Redis 出错时由本地限速器判断
+func (rl *RedisLimiter) AllowN(now time.Time, n int) bool {
+	if rl.limit == Inf || n <= 0 {
+		return true
+	}
+	if n > rl.burst {
+		return false
+	}
+	ok, _, err := rl.take(now, n)
+	if err != nil {
+		return rl.fallback.AllowN(now, n)
+	}
+	return ok
+}
+
+func (rl *RedisLimiter) Wait(ctx context.Context) error {
+	return rl.WaitN(ctx, 1)
+}
+
+// WaitN 阻塞，直到 Limiter 允许n个事件发生
+// 令牌不足时按 Redis 返回的等待时间休眠后重试, 其他进程可能在此期间取走令牌
+func (rl *RedisLimiter) WaitN(ctx context.Context, n int) error {
+	if n > rl.burst && rl.limit != Inf {
+		return fmt.Errorf("rate: Wait(n=%d) exceeds limiter's burst %d", n, rl.burst)
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+		if rl.limit == Inf || n <= 0 {
+			return nil
+		}
+
+		now := rl.clock.Now()
+		ok, delay, err := rl.take(now, n)
+		if err != nil {
+			return rl.fallback.WaitN(ctx, n)
+		}
+		if ok {
+			return nil
+		}
+		if deadline, has := ctx.Deadline(); delay == InfDuration || has && now.Add(delay).After(deadline) {
+			return fmt.Errorf("rate: Wait(n=%d) would exceed context deadline", n)
+		}
+
+		t := rl.clock.NewTimer(delay)
+		select {
+		case <-t.C():
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+// take 尝试从 Redis 中取走n个令牌, 失败时返回还需等待的时间
+func (rl *RedisLimiter) take(now time.Time, n int) (bool, time.Duration, error) {
+	res, err := tokenBucketScript.Run(rl.client, []string{rl.key},
+		float64(rl.limit), rl.burst, now.UnixNano()/int64(time.Microsecond), n, rl.ttl().Milliseconds()).Result()
+	if err != nil {
+		return false, 0, err
+	}
+	values, ok := res.([]interface{})
+	if !ok || len(values) != 2 {
+		return false, 0, fmt.Errorf("rate: unexpected script result %v", res)
+	}
+	allowed, _ := values[0].(int64)
+	wait, _ := values[1].(int64)
+	if allowed == 1 {
+		return true, 0, nil
+	}
+	if wait < 0 {
+		return false, InfDuration, nil
+	}
+	return false, time.Duration(wait) * time.Microsecond, nil
+}
+
+// ttl 令牌桶从空到满所需的时间, 之后 key 与满桶等价, 可以过期删除
+func (rl *RedisLimiter) ttl() time.Duration {
+	if rl.limit <= 0 {
+		return 0
+	}
+	fill := rl.limit.durationFromTokens(float64(rl.burst))
+	return time.Duration(math.Ceil(float64(fill)/float64(time.Millisecond)))*time.Millisecond + time.Second
+}
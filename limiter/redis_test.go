@@ -0,0 +1,127 @@
+package limiter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis"
+)
+
+func newRedisClient(t *testing.T) (*miniredis.Miniredis, *redis.Client) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() {
+		_ = client.Close()
+	})
+	return mr, client
+}
+
+func TestRedisLimiter_AllowN(t *testing.T) {
+	_, client := newRedisClient(t)
+	runRedisLimit(t, NewRedisLimiter(client, "allow", 10, 2), []allow{
+		{t0, 1, true},
+		{t0, 1, true},
+		{t0, 1, false},
+		{t1, 1, true},
+		{t1, 1, false},
+		{t2, 3, false}, // burst size is 2, so n=3 always fails
+		{t2, 1, true},
+		{t3, 2, false},
+		{t4, 2, true},
+		{t4, -10, true},
+		{t4, 1, false},
+	})
+}
+
+func TestRedisLimiter_Shared(t *testing.T) {
+	_, client := newRedisClient(t)
+	a := NewRedisLimiter(client, "shared", 10, 2)
+	b := NewRedisLimiter(client, "shared", 10, 2)
+	other := NewRedisLimiter(client, "other", 10, 2)
+
+	runRedisLimit(t, a, []allow{{t0, 1, true}})
+	runRedisLimit(t, b, []allow{{t0, 1, true}, {t0, 1, false}})
+	runRedisLimit(t, a, []allow{{t0, 1, false}, {t1, 1, true}})
+	runRedisLimit(t, other, []allow{{t0, 2, true}})
+}
+
+func TestRedisLimiter_ZeroLimit(t *testing.T) {
+	_, client := newRedisClient(t)
+	lim := NewRedisLimiter(client, "zero", 0, 1)
+	// 与 NewLimiter(0, 1) 一致, 从一开始就拒绝所有事件
+	runRedisLimit(t, lim, []allow{
+		{t0, 1, false},
+		{t9, 1, false},
+		{t9, 0, true},
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := lim.Wait(ctx); err == nil {
+		t.Errorf("Wait on zero limit should fail")
+	}
+}
+
+func TestRedisLimiter_Expire(t *testing.T) {
+	mr, client := newRedisClient(t)
+	NewRedisLimiter(client, "expire", 10, 2).Allow()
+	if ttl := mr.TTL("expire"); ttl <= 0 || ttl > 2*time.Second {
+		t.Errorf("ttl = %v, want (0, 2s]", ttl)
+	}
+}
+
+func TestRedisLimiter_Wait(t *testing.T) {
+	_, client := newRedisClient(t)
+	clock := NewFakeClock(time.Now())
+	lim := NewRedisLimiter(client, "wait", 10, 1, WithClock(clock))
+	ctx := context.Background()
+
+	if err := lim.Wait(ctx); err != nil {
+		t.Fatalf("first Wait: %v", err)
+	}
+	if err := waitAfter(t, clock, d, func() error {
+		return lim.Wait(ctx)
+	}); err != nil {
+		t.Errorf("second Wait: %v", err)
+	}
+
+	if err := lim.WaitN(ctx, 2); err == nil {
+		t.Errorf("WaitN(2) with burst 1 should fail")
+	}
+
+	ctx, cancel := context.WithDeadline(ctx, clock.Now().Add(d/2))
+	defer cancel()
+	if err := lim.Wait(ctx); err == nil {
+		t.Errorf("Wait beyond deadline should fail")
+	}
+}
+
+func TestRedisLimiter_Fallback(t *testing.T) {
+	mr, client := newRedisClient(t)
+	fallback := NewLimiter(10, 1)
+	lim := NewRedisLimiter(client, "fallback", 10, 2, WithFallback(fallback))
+	runRedisLimit(t, lim, []allow{{t0, 2, true}, {t0, 1, false}})
+
+	mr.Close()
+	runRedisLimit(t, lim, []allow{
+		{t0, 1, true},
+		{t0, 1, false},
+		{t1, 1, true},
+	})
+	if err := lim.Wait(context.Background()); err != nil {
+		t.Errorf("Wait with fallback: %v", err)
+	}
+}
+
+func runRedisLimit(t *testing.T, lim *RedisLimiter, allows []allow) {
+	t.Helper()
+	for i, allow := range allows {
+		ok := lim.AllowN(allow.t, allow.n)
+		if ok != allow.ok {
+			t.Errorf("step %d: lim.AllowN(%v, %v) = %v want %v",
+				i, allow.t, allow.n, ok, allow.ok)
+		}
+	}
+}
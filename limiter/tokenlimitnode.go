@@ -1,6 +1,8 @@
 package limiter
 
 import (
+	"context"
+	"fmt"
 	"golang.org/x/time/rate"
 	"time"
 )
@@ -10,19 +12,22 @@ type (
 		limit float64
 		burst int
 		lim   *rate.Limiter
+		clock Clock
 	}
 )
 
-func NewNodeTokenLimiter(limit float64, burst int) *NodeTokenLimiter {
+func NewNodeTokenLimiter(limit float64, burst int, opts ...OptFn) *NodeTokenLimiter {
+	op := applyOptions(options{}, opts)
 	return &NodeTokenLimiter{
 		limit: limit,
 		burst: burst,
 		lim:   rate.NewLimiter(rate.Limit(limit), burst),
+		clock: op.clock,
 	}
 }
 
 func (nl *NodeTokenLimiter) Allow() bool {
-	return nl.AllowN(time.Now(), 1)
+	return nl.AllowN(nl.clock.Now(), 1)
 }
 
 // AllowN 重写 rate.AllowN方法 修复人为可通过设置n<0的数来反向增加令牌
@@ -32,3 +37,41 @@ func (nl *NodeTokenLimiter) AllowN(now time.Time, n int) bool {
 	}
 	return nl.lim.AllowN(now, n)
 }
+
+// Wait 使用 nl 的时钟等待, 而不是 rate.Limiter.Wait 中的系统时钟
+func (nl *NodeTokenLimiter) Wait(ctx context.Context) error {
+	if nl.burst < 1 && rate.Limit(nl.limit) != rate.Inf {
+		return fmt.Errorf("rate: Wait(n=1) exceeds limiter's burst %d", nl.burst)
+	}
+	return waitN(ctx, 1, nl.clock, func(now time.Time, n int, maxFutureReserve time.Duration) Reservation {
+		r := nl.ReserveN(now, n)
+		if r.Ok() && r.DelayFrom(now) > maxFutureReserve {
+			r.CancelAt(now)
+			return Reservation{}
+		}
+		return *r
+	})
+}
+
+func (nl *NodeTokenLimiter) Reserve() *Reservation {
+	return nl.ReserveN(nl.clock.Now(), 1)
+}
+
+// ReserveN 将 rate.Reservation 转换为 Reservation, n<0 时与 AllowN 一样直接允许
+func (nl *NodeTokenLimiter) ReserveN(now time.Time, n int) *Reservation {
+	if n < 0 {
+		return &Reservation{ok: true, timeToAct: now, clock: nl.clock}
+	}
+	r := nl.lim.ReserveN(now, n)
+	if !r.OK() {
+		return &Reservation{ok: false}
+	}
+	return &Reservation{
+		ok:        true,
+		tokens:    n,
+		timeToAct: now.Add(r.DelayFrom(now)),
+		limit:     Limit(nl.limit),
+		cancel:    r.CancelAt,
+		clock:     nl.clock,
+	}
+}
@@ -28,3 +28,19 @@ func runNodeLimit(t *testing.T, lim *NodeTokenLimiter, allows []allow) {
 		}
 	}
 }
+
+func TestTokenBucketLimiter_Reserve(t *testing.T) {
+	lim := NewNodeTokenLimiter(10, 1)
+	if r := lim.ReserveN(t0, 1); !r.Ok() || r.DelayFrom(t0) != 0 {
+		t.Errorf("first reservation = %v, %v want true, 0", r.Ok(), r.DelayFrom(t0))
+	}
+	r := lim.ReserveN(t0, 1)
+	if !r.Ok() || r.DelayFrom(t0) != d {
+		t.Errorf("second reservation = %v, %v want true, %v", r.Ok(), r.DelayFrom(t0), d)
+	}
+	r.CancelAt(t0)
+	runNodeLimit(t, lim, []allow{{t1, 1, true}})
+	if r := lim.ReserveN(t0, 2); r.Ok() {
+		t.Errorf("reservation exceeding burst should fail")
+	}
+}
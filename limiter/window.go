@@ -0,0 +1,254 @@
+package limiter
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+type (
+	// SlidingWindowLog 滑动窗口日志, 记录窗口内每次允许的事件, 任意 window 时间内最多允许 limit 个事件
+	SlidingWindowLog struct {
+		mu     sync.Mutex
+		limit  int
+		window time.Duration
+		// events 已允许的事件, 按时间排序, 预约的事件时间可能晚于当前时间
+		events []windowEvent
+		count  int
+		clock  Clock
+	}
+
+	windowEvent struct {
+		at time.Time
+		n  int
+	}
+
+	// SlidingWindowCounter 滑动窗口计数, 只记录每个固定窗口的事件数
+	// 当前窗口的事件数加上前一个窗口按未过去的比例折算的事件数不超过 limit
+	SlidingWindowCounter struct {
+		mu     sync.Mutex
+		limit  int
+		window time.Duration
+		// counts 各固定窗口内的事件数, key 为窗口序号
+		counts map[int64]int
+		clock  Clock
+	}
+)
+
+// NewSlidingWindowLog 实例化一个滑动窗口日志限速器, window 小于等于 0 时不限速
+func NewSlidingWindowLog(limit int, window time.Duration, opts ...OptFn) *SlidingWindowLog {
+	op := applyOptions(options{}, opts)
+	return &SlidingWindowLog{
+		limit:  limit,
+		window: window,
+		clock:  op.clock,
+	}
+}
+
+func (w *SlidingWindowLog) Allow() bool {
+	return w.AllowN(w.clock.Now(), 1)
+}
+
+func (w *SlidingWindowLog) AllowN(now time.Time, n int) bool {
+	return w.reserveN(now, n, 0).ok
+}
+
+func (w *SlidingWindowLog) Reserve() *Reservation {
+	return w.ReserveN(w.clock.Now(), 1)
+}
+
+func (w *SlidingWindowLog) ReserveN(now time.Time, n int) *Reservation {
+	r := w.reserveN(now, n, InfDuration)
+	return &r
+}
+
+func (w *SlidingWindowLog) Wait(ctx context.Context) error {
+	return w.WaitN(ctx, 1)
+}
+
+func (w *SlidingWindowLog) WaitN(ctx context.Context, n int) error {
+	if n > w.limit && w.window > 0 {
+		return fmt.Errorf("rate: Wait(n=%d) exceeds window limit %d", n, w.limit)
+	}
+	return waitN(ctx, n, w.clock, w.reserveN)
+}
+
+func (w *SlidingWindowLog) reserveN(now time.Time, n int, maxFutureReserve time.Duration) Reservation {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.window <= 0 || n <= 0 {
+		return Reservation{ok: true, tokens: n, timeToAct: now, clock: w.clock}
+	}
+	if n > w.limit {
+		return Reservation{}
+	}
+
+	// 丢弃已经滑出窗口的事件
+	var expired int
+	for expired < len(w.events) && !w.events[expired].at.Add(w.window).After(now) {
+		w.count -= w.events[expired].n
+		expired++
+	}
+	w.events = w.events[expired:]
+
+	start := w.earliest(now, n)
+	if start.Sub(now) > maxFutureReserve {
+		return Reservation{}
+	}
+
+	w.events = append(w.events, windowEvent{at: start, n: n})
+	w.count += n
+	return Reservation{
+		ok:        true,
+		tokens:    n,
+		timeToAct: start,
+		clock:     w.clock,
+		cancel: func(now time.Time) {
+			w.mu.Lock()
+			defer w.mu.Unlock()
+			if !start.After(now) {
+				return
+			}
+			for i := len(w.events) - 1; i >= 0; i-- {
+				if e := w.events[i]; e.at.Equal(start) && e.n == n {
+					w.events = append(w.events[:i], w.events[i+1:]...)
+					w.count -= n
+					return
+				}
+			}
+		},
+	}
+}
+
+// earliest 最早能容纳 n 个事件的时间, n 不能超过 limit, 不改变状态
+func (w *SlidingWindowLog) earliest(now time.Time, n int) time.Time {
+	// 事件按时间排序, 不早于最后一个预约的事件
+	start := now
+	if last := len(w.events) - 1; last >= 0 && w.events[last].at.After(start) {
+		start = w.events[last].at
+	}
+	// 等待最早的事件依次滑出窗口, 直到能容纳n个事件
+	i, inWindow := 0, w.count
+	for {
+		for i < len(w.events) && !w.events[i].at.Add(w.window).After(start) {
+			inWindow -= w.events[i].n
+			i++
+		}
+		if inWindow+n <= w.limit {
+			return start
+		}
+		start = w.events[i].at.Add(w.window)
+	}
+}
+
+// NewSlidingWindowCounter 实例化一个滑动窗口计数限速器, window 小于等于 0 时不限速
+func NewSlidingWindowCounter(limit int, window time.Duration, opts ...OptFn) *SlidingWindowCounter {
+	op := applyOptions(options{}, opts)
+	return &SlidingWindowCounter{
+		limit:  limit,
+		window: window,
+		counts: make(map[int64]int),
+		clock:  op.clock,
+	}
+}
+
+func (w *SlidingWindowCounter) Allow() bool {
+	return w.AllowN(w.clock.Now(), 1)
+}
+
+func (w *SlidingWindowCounter) AllowN(now time.Time, n int) bool {
+	return w.reserveN(now, n, 0).ok
+}
+
+func (w *SlidingWindowCounter) Reserve() *Reservation {
+	return w.ReserveN(w.clock.Now(), 1)
+}
+
+func (w *SlidingWindowCounter) ReserveN(now time.Time, n int) *Reservation {
+	r := w.reserveN(now, n, InfDuration)
+	return &r
+}
+
+func (w *SlidingWindowCounter) Wait(ctx context.Context) error {
+	return w.WaitN(ctx, 1)
+}
+
+func (w *SlidingWindowCounter) WaitN(ctx context.Context, n int) error {
+	if n > w.limit && w.window > 0 {
+		return fmt.Errorf("rate: Wait(n=%d) exceeds window limit %d", n, w.limit)
+	}
+	return waitN(ctx, n, w.clock, w.reserveN)
+}
+
+func (w *SlidingWindowCounter) reserveN(now time.Time, n int, maxFutureReserve time.Duration) Reservation {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.window <= 0 || n <= 0 {
+		return Reservation{ok: true, tokens: n, timeToAct: now, clock: w.clock}
+	}
+	if n > w.limit {
+		return Reservation{}
+	}
+
+	window := int64(w.window)
+	for idx := range w.counts {
+		if idx < now.UnixNano()/window-1 {
+			delete(w.counts, idx)
+		}
+	}
+
+	k, waitDuration := w.earliest(now, n)
+	if waitDuration > maxFutureReserve {
+		return Reservation{}
+	}
+
+	w.counts[k] += n
+	timeToAct := now.Add(waitDuration)
+	return Reservation{
+		ok:        true,
+		tokens:    n,
+		timeToAct: timeToAct,
+		clock:     w.clock,
+		cancel: func(now time.Time) {
+			w.mu.Lock()
+			defer w.mu.Unlock()
+			if !timeToAct.After(now) {
+				return
+			}
+			if w.counts[k] -= n; w.counts[k] <= 0 {
+				delete(w.counts, k)
+			}
+		},
+	}
+}
+
+// earliest 最早能容纳 n 个事件的窗口序号和需要等待的时间, n 不能超过 limit, 不改变状态
+func (w *SlidingWindowCounter) earliest(now time.Time, n int) (int64, time.Duration) {
+	window := int64(w.window)
+	k := now.UnixNano() / window
+	elapsed := time.Duration(now.UnixNano() - k*window)
+
+	// 从当前窗口开始, 找到最早能容纳n个事件的时间
+	for {
+		prev, curr := w.counts[k-1], w.counts[k]
+		if curr+n <= w.limit {
+			// prev*(window-need)/window + curr + n <= limit
+			var need time.Duration
+			if prev > 0 {
+				need = time.Duration(math.Ceil(float64(w.window) * (1 - float64(w.limit-curr-n)/float64(prev))))
+			}
+			if need < elapsed {
+				need = elapsed
+			}
+			if need < w.window {
+				return k, time.Duration(k*window-now.UnixNano()) + need
+			}
+		}
+		k++
+		elapsed = 0
+	}
+}
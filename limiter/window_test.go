@@ -0,0 +1,71 @@
+package limiter
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSlidingWindowLog_Allow(t *testing.T) {
+	run(t, NewSlidingWindowLog(2, 3*d), []allow{
+		{t0, 1, true},
+		{t1, 1, true},
+		{t2, 1, false},
+		{t3, 1, true}, // t0 滑出窗口
+		{t3, 1, false},
+		{t4, 3, false}, // limit is 2, so n=3 always fails
+		{t4, 1, true},  // t1 滑出窗口
+		{t4, -10, true},
+		{t9, 2, true},
+		{t9, 1, false},
+	})
+}
+
+func TestSlidingWindowLog_Reserve(t *testing.T) {
+	lim := NewSlidingWindowLog(2, 3*d)
+	lim.ReserveN(t0, 1)
+	lim.ReserveN(t1, 1)
+	r := lim.ReserveN(t1, 1)
+	if delay := r.DelayFrom(t1); delay != 2*d {
+		t.Errorf("delay = %v want %v", delay, 2*d)
+	}
+	if delay := lim.ReserveN(t1, 1).DelayFrom(t1); delay != 3*d {
+		t.Errorf("delay = %v want %v", delay, 3*d)
+	}
+	r.CancelAt(t1)
+	if lim.count != 3 {
+		t.Errorf("count after cancel = %d want 3", lim.count)
+	}
+}
+
+func TestSlidingWindowCounter_Allow(t *testing.T) {
+	base := time.Unix(1000, 0)
+	at := func(n int) time.Time {
+		return base.Add(time.Duration(n) * d)
+	}
+	run(t, NewSlidingWindowCounter(4, 10*d), []allow{
+		{at(0), 4, true},
+		{at(5), 1, false},
+		{at(10), 1, false}, // 前一个窗口的 4 个事件全部计入
+		{at(13), 1, true},  // 4*0.7 + 0 + 1 <= 4
+		{at(13), 1, false}, // 4*0.7 + 1 + 1 > 4
+		{at(15), 1, true},  // 4*0.5 + 1 + 1 <= 4
+		{at(15), 5, false}, // limit is 4, so n=5 always fails
+		{at(20), 2, true},  // 2*1.0 + 0 + 2 <= 4
+		{at(40), 4, true},
+	})
+}
+
+func TestSlidingWindowCounter_Reserve(t *testing.T) {
+	base := time.Unix(1000, 0)
+	lim := NewSlidingWindowCounter(4, 10*d)
+	lim.ReserveN(base, 4)
+	r := lim.ReserveN(base, 2)
+	// 下一个窗口内 4*(1-x) + 2 <= 4, x >= 0.5
+	if delay := r.DelayFrom(base); delay != 15*d {
+		t.Errorf("delay = %v want %v", delay, 15*d)
+	}
+	r.CancelAt(base)
+	if r := lim.ReserveN(base, 2); r.DelayFrom(base) != 15*d {
+		t.Errorf("delay after cancel = %v want %v", r.DelayFrom(base), 15*d)
+	}
+}
@@ -15,3 +15,9 @@ type YMLConfiger interface {
 	GetDuration(keyName string) time.Duration
 	GetStringSlice(keyName string) []string
 }
+
+// ChangeNotifier 能够在配置文件变化后回调的配置, CreateYamlFactory 等创建的 YMLConfiger 都实现了该接口
+type ChangeNotifier interface {
+	// OnConfigChange 注册配置文件变化后的回调, 回调时缓存已经清空, 需要先调用 ConfigFileChangeListen 开始监听
+	OnConfigChange(fn func())
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/Fighting2520/go-common/container"
 	"io"
 	"log"
+	"sync"
 	"time"
 
 	"github.com/fsnotify/fsnotify"
@@ -22,10 +23,19 @@ var (
 	ErrInitConfigFail = errors.New("初始化配置文件错误")
 )
 
+var _ ChangeNotifier = (*ymlConfig)(nil)
+
 type (
 	ymlConfig struct {
 		viper     *viper.Viper
 		keyPrefix string
+		hooks     *changeHooks
+	}
+
+	// changeHooks 配置文件变化后的回调
+	changeHooks struct {
+		mu  sync.Mutex
+		fns []func()
 	}
 )
 
@@ -34,6 +44,7 @@ func (y *ymlConfig) Clone(fileName string) YMLConfiger {
 	var ymlCopy = *y
 	var ymlViperCopy = *(y.viper)
 	ymlCopy.viper = &ymlViperCopy
+	ymlCopy.hooks = &changeHooks{}
 	ymlCopy.viper.SetConfigName(fileName)
 	if err := ymlCopy.viper.ReadInConfig(); err != nil {
 		//global.ZapLog.Error(ErrInitConfigFail.Error(), zap.Error(err))
@@ -149,6 +160,7 @@ func CreateYamlFactoryFromReader(reader io.Reader, keyPrefix string) YMLConfiger
 	return &ymlConfig{
 		viper:     v,
 		keyPrefix: keyPrefix,
+		hooks:     &changeHooks{},
 	}
 }
 
@@ -170,6 +182,7 @@ func CreateYamlFactory(configPath, keyPrefix string, filename ...string) YMLConf
 	return &ymlConfig{
 		viper:     yamlConfig,
 		keyPrefix: keyPrefix,
+		hooks:     &changeHooks{},
 	}
 }
 
@@ -180,12 +193,29 @@ func (y *ymlConfig) ConfigFileChangeListen() {
 			if in.Op.String() == "WRITE" {
 				y.clearCache()
 				lastChangeTime = time.Now()
+				y.hooks.run()
 			}
 		}
 	})
 	y.viper.WatchConfig()
 }
 
+// OnConfigChange 注册配置文件变化后的回调
+func (y *ymlConfig) OnConfigChange(fn func()) {
+	y.hooks.mu.Lock()
+	defer y.hooks.mu.Unlock()
+	y.hooks.fns = append(y.hooks.fns, fn)
+}
+
+func (h *changeHooks) run() {
+	h.mu.Lock()
+	fns := append([]func(){}, h.fns...)
+	h.mu.Unlock()
+	for _, fn := range fns {
+		fn()
+	}
+}
+
 func (y *ymlConfig) clearCache() {
 	container.CreateContainerFactory().FuzzyDelete(y.keyPrefix)
 }